@@ -0,0 +1,827 @@
+// Package angular formats Angular templates: it puts the braces of control
+// flow blocks (@if, @for, @switch, ...) on lines of their own and indents the
+// blocks' bodies. It is the custom HTML pass of go-formatter and has no
+// dependencies outside the standard library, so other Go tools can import it.
+package angular
+
+import (
+    "fmt"
+    "slices"
+    "strconv"
+    "strings"
+)
+
+// Brace styles for Options.BraceStyle.
+const (
+    BraceAllman = "allman" // "{" on a line of its own
+    BraceKR     = "k&r"    // "{" at the end of the directive line
+)
+
+const defaultIndent = "    "
+
+// defaultMaxBlankLines is what a zero MaxBlankLines stands for.
+const defaultMaxBlankLines = 1
+
+// Options configures FormatAngularTemplate. The zero value formats like the
+// CLI without flags: Allman braces, a 4-space indent, at most one blank line
+// in a row and a final newline.
+type Options struct {
+    // Indent is added per brace level: spaces or "\t" (see ParseIndent).
+    // Empty means 4 spaces. A template indented mostly with tabs is
+    // indented with tabs regardless.
+    Indent string
+    // BraceStyle is BraceAllman or BraceKR; anything else is Allman.
+    // Closing braces are on their own line either way.
+    BraceStyle string
+    // MaxBlankLines caps a run of consecutive blank lines; 0 means 1.
+    // Blank lines inside <pre>, <textarea>, <script>, <style> and comments
+    // are content and are never collapsed. Negative keeps every blank line.
+    MaxBlankLines int
+    // NoBlankLines removes every blank line that is not content, whatever
+    // MaxBlankLines says.
+    NoBlankLines bool
+    // KeepMissingNewline leaves a template that lacks a trailing newline
+    // without one instead of adding it. A newline that is already there is
+    // always kept.
+    KeepMissingNewline bool
+}
+
+// DefaultOptions returns the settings go-formatter uses without flags, which
+// are also what the zero Options means.
+func DefaultOptions() Options {
+    return Options{Indent: defaultIndent, BraceStyle: BraceAllman, MaxBlankLines: defaultMaxBlankLines}
+}
+
+// ParseIndent turns an -indent value (a number of spaces or "tab") into the
+// literal indent string.
+func ParseIndent(value string) (string, error) {
+    if strings.EqualFold(value, "tab") {
+        return "\t", nil
+    }
+    n, err := strconv.Atoi(value)
+    if err != nil || n < 1 || n > 16 {
+        return "", fmt.Errorf("expected a number of spaces (1-16) or 'tab', got %q", value)
+    }
+    return strings.Repeat(" ", n), nil
+}
+
+// UnbalancedBraceError reports a "}" with no open block, typically a
+// template caught mid-edit. Formatting past it would misindent the rest of the
+// file, so the content is left unchanged instead.
+type UnbalancedBraceError struct {
+    Line int
+}
+
+func (e *UnbalancedBraceError) Error() string {
+    return fmt.Sprintf("line %d: '}' closes more blocks than were opened", e.Line)
+}
+
+// FormatAngularTemplate expands Angular control flow blocks (@if, @for,
+// @switch, @defer, ...) so each brace gets a line of its own (or, with
+// BraceKR, keeps "{" on the directive line) and re-indents the block bodies.
+// It handles:
+// - Nested parentheses like adminTypes()
+// - @else and @else if patterns
+// - Multiple closing braces on one line (} } or } } })
+// - Preserves {{ }} interpolation
+// - Preserves HTML comments
+//
+// It expects Prettier-formatted HTML. A template with a "}" that closes
+// nothing returns an *UnbalancedBraceError.
+func FormatAngularTemplate(content string, opts Options) (string, error) {
+    indent := opts.Indent
+    if indent == "" {
+        indent = defaultIndent
+    }
+    maxBlankLines := opts.MaxBlankLines
+    switch {
+    case opts.NoBlankLines:
+        maxBlankLines = 0
+    case maxBlankLines == 0:
+        maxBlankLines = defaultMaxBlankLines
+    }
+
+    // Work on LF internally and restore CRLF on the way out so Windows files
+    // round-trip instead of ending up with mixed line endings
+    crlf := strings.Contains(content, "\r\n")
+    if crlf {
+        content = strings.ReplaceAll(content, "\r\n", "\n")
+    }
+
+    // Split without the final newline so it can't turn into a stray
+    // indented line, and decide separately whether the output gets one
+    endsWithNewline := strings.HasSuffix(content, "\n")
+    lines := strings.Split(strings.TrimSuffix(content, "\n"), "\n")
+    var result []string
+
+    // Depth indent and the original indent must be the same kind of
+    // whitespace, or lines end up starting with a mix of tabs and spaces
+    indent = fileIndentUnit(lines, indent)
+
+    depth := 0
+    // Blocks open at this point, innermost last
+    var open []openBlock
+    inComment := false
+    inVerbatim := "" // "pre", "textarea", "script" or "style" while inside one
+    inInterpolation := false
+    // A directive header wrapped over several lines ("@for (\n item of
+    // items;\n track item.id\n) {"): parens still open, and the indent of
+    // the line the directive started on
+    headerParens := 0
+    headerIndent := ""
+    // An opening tag whose attributes Prettier wrapped onto the next lines
+    inTag := false
+    var tagQuote byte
+
+    for lineNo, originalLine := range lines {
+        trimmed := strings.TrimSpace(originalLine)
+        originalIndent := normalizeIndent(extractIndent(originalLine), indent)
+        afterHeader := false // trimmed is what followed a wrapped header's "{"
+
+        // Attribute lines of a wrapped tag move with the line that opened it:
+        // same depth shift, never expanded or counted as braces
+        if inTag {
+            if trimmed == "" {
+                result = append(result, "")
+            } else {
+                result = append(result, strings.Repeat(indent, depth)+originalIndent+trimmed)
+            }
+            inTag, tagQuote = tagLeftOpen(trimmed, true, tagQuote)
+            continue
+        }
+
+        // Lines of a wrapped header move with its directive line. Once the
+        // parens close, the brace after them opens the block like it would
+        // on a one-line header, and anything after that is formatted as usual.
+        if headerParens > 0 {
+            end, parens := closingParen(trimmed, headerParens)
+            if end < 0 {
+                headerParens = parens
+                result = append(result, strings.Repeat(indent, depth)+originalIndent+trimmed)
+                continue
+            }
+            headerParens = 0
+            head, rest := trimmed[:end+1], strings.TrimSpace(trimmed[end+1:])
+            if body, ok := strings.CutPrefix(rest, "{"); ok {
+                rest = strings.TrimSpace(body)
+                switch {
+                case opts.BraceStyle != BraceKR:
+                    result = append(result, strings.Repeat(indent, depth)+originalIndent+head)
+                    result = append(result, strings.Repeat(indent, depth)+headerIndent+"{")
+                    open = append(open, openBlock{true, headerIndent})
+                    depth++
+                case rest == "":
+                    result = append(result, strings.Repeat(indent, depth)+originalIndent+head+" {")
+                    open = append(open, openBlock{false, headerIndent})
+                default:
+                    result = append(result, strings.Repeat(indent, depth)+originalIndent+head+" {")
+                    open = append(open, openBlock{true, headerIndent})
+                    depth++
+                }
+            } else {
+                result = append(result, strings.Repeat(indent, depth)+originalIndent+head)
+            }
+            if rest == "" {
+                continue
+            }
+            trimmed, originalIndent = rest, headerIndent
+            afterHeader = true
+        }
+
+        // Continuation lines of a multi-line {{ }} keep their own alignment
+        // (e.g. a column of "| pipe" lines) - preserve exactly until "}}"
+        if inInterpolation {
+            result = append(result, originalLine)
+            if strings.Contains(trimmed, "}}") {
+                inInterpolation = false
+            }
+            continue
+        }
+
+        // Whitespace inside <pre>/<textarea> is content, and so are the
+        // braces of inline CSS and JavaScript - preserve exactly, blank lines
+        // included, until the closing tag
+        if inVerbatim != "" {
+            result = append(result, originalLine)
+            if strings.Contains(strings.ToLower(trimmed), "</"+inVerbatim) {
+                inVerbatim = ""
+            }
+            continue
+        }
+        if tag := openVerbatimTag(trimmed); tag != "" {
+            inVerbatim = tag
+            result = append(result, strings.Repeat(indent, depth)+originalIndent+trimmed)
+            continue
+        }
+
+        if trimmed == "" {
+            if !inComment && maxBlankLines >= 0 && trailingBlanks(result) >= maxBlankLines {
+                continue
+            }
+            result = append(result, "")
+            continue
+        }
+
+        // Track multi-line HTML comments - preserve exactly
+        if strings.Contains(trimmed, "<!--") && !strings.Contains(trimmed, "-->") {
+            inComment = true
+            result = append(result, originalLine)
+            continue
+        }
+        if inComment {
+            result = append(result, originalLine)
+            if strings.Contains(trimmed, "-->") {
+                inComment = false
+            }
+            continue
+        }
+
+        // The line itself is formatted as usual; only what follows is verbatim
+        inInterpolation = opensInterpolation(trimmed)
+        // depth is final for the line once it's processed below, which is
+        // where the tag's remaining lines pick it up
+        inTag, tagQuote = tagLeftOpen(trimmed, false, 0)
+
+        // A '}' closing a block whose '{' stood alone (or ended a K&R
+        // directive line) leaves the depth alone; peel it off before
+        // expanding the rest, e.g. the "@else {" of "} @else {". Like any
+        // '}' that closes a block from an earlier line, it and the branch
+        // after it line up with the block's directive.
+        lineIndent := originalIndent
+        peeled := false
+        for len(open) > 0 && !open[len(open)-1].indented && strings.HasPrefix(trimmed, "}") && trimmed != "}" {
+            originalIndent = open[len(open)-1].indent
+            open = open[:len(open)-1]
+            result = append(result, strings.Repeat(indent, depth)+originalIndent+"}")
+            trimmed = strings.TrimSpace(trimmed[1:])
+            peeled = true
+        }
+
+        // A directive whose header continues on the next lines
+        if strings.HasPrefix(trimmed, "@") && isControlFlowDirective(trimmed) {
+            if end, parens := closingParen(trimmed, 0); end < 0 && parens > 0 {
+                headerParens, headerIndent = parens, originalIndent
+                result = append(result, strings.Repeat(indent, depth)+originalIndent+trimmed)
+                continue
+            }
+        }
+
+        // Check if this line needs expansion
+        needsExpand := afterHeader || (strings.Contains(trimmed, "@") && isControlFlowLine(trimmed)) ||
+            strings.Contains(trimmed, "} }")
+
+        if !needsExpand {
+            // Check for standalone }
+            if trimmed == "{" {
+                open = append(open, openBlock{false, originalIndent})
+                // K&R: pull an Allman brace up onto its directive line
+                if opts.BraceStyle == BraceKR && len(result) > 0 && isBareDirective(result[len(result)-1]) {
+                    result[len(result)-1] += " {"
+                    continue
+                }
+            }
+            if trimmed == "}" {
+                if len(open) == 0 {
+                    return "", &UnbalancedBraceError{Line: lineNo + 1}
+                }
+                if open[len(open)-1].indented {
+                    depth--
+                }
+                open = open[:len(open)-1]
+                extraIndent := strings.Repeat(indent, depth)
+                result = append(result, extraIndent+originalIndent+trimmed)
+                continue
+            }
+
+            // Regular line - add depth-based indent
+            extraIndent := strings.Repeat(indent, depth)
+            result = append(result, extraIndent+originalIndent+trimmed)
+            continue
+        }
+
+        // Expand this line
+        expanded := expandLineWithIndent(trimmed, originalIndent, lineIndent, open, peeled, depth, indent, opts.BraceStyle)
+        var ok bool
+        if delta := expanded.finalDepth - depth; delta < 0 {
+            open, _ = dropOpen(open, true, -delta)
+        } else {
+            for range delta {
+                open = append(open, openBlock{true, expanded.indent})
+            }
+        }
+        // Closes past this pass's own blocks end ones that were already on their own line
+        if open, ok = dropOpen(open, false, expanded.unmatched); !ok {
+            return "", &UnbalancedBraceError{Line: lineNo + 1}
+        }
+        if expanded.bareOpen {
+            open = append(open, openBlock{false, expanded.indent})
+        }
+
+        for _, expLine := range expanded.lines {
+            result = append(result, expLine)
+        }
+
+        depth = expanded.finalDepth
+    }
+
+    output := strings.Join(result, "\n")
+    if content != "" && (endsWithNewline || !opts.KeepMissingNewline) {
+        output += "\n"
+    }
+    if crlf {
+        output = strings.ReplaceAll(output, "\n", "\r\n")
+    }
+    return output, nil
+}
+
+// openBlock is a block that is open at the current line.
+type openBlock struct {
+    // indented is true for a block this pass indents, false for a "{"
+    // already on its own line (previously formatted output), whose body
+    // keeps the indentation it has
+    indented bool
+    indent   string // original indent of the line the block's directive is on
+}
+
+// dropOpen removes the innermost n entries of kind from open. It reports
+// false if there are fewer than n.
+func dropOpen(open []openBlock, kind bool, n int) ([]openBlock, bool) {
+    for i := len(open) - 1; i >= 0 && n > 0; i-- {
+        if open[i].indented == kind {
+            open = slices.Delete(open, i, i+1)
+            n--
+        }
+    }
+    return open, n == 0
+}
+
+type expandResult struct {
+    lines      []string
+    finalDepth int
+    unmatched  int    // '}' that closed a block whose '{' stood alone, or nothing at all
+    bareOpen   bool   // K&R: the line ends with a "{" whose body is on the lines below
+    indent     string // original indent for the blocks the line leaves open
+}
+
+// isBareDirective reports whether an output line is a control flow directive
+// whose '{' has not been written yet, e.g. "@if (a)" in Allman style.
+func isBareDirective(line string) bool {
+    trimmed := strings.TrimSpace(line)
+    return strings.HasPrefix(trimmed, "@") && isControlFlowDirective(trimmed) && !strings.HasSuffix(trimmed, "{")
+}
+
+// isControlFlowLine reports whether a line opens or continues a control flow
+// block. Every directive counts, including @case/@default/@empty on their own
+// line, so the brace they open is tracked in the depth.
+func isControlFlowLine(trimmed string) bool {
+    if strings.Contains(trimmed, "{") {
+        for i := 0; i < len(trimmed); i++ {
+            if end := verbatimSpanEnd(trimmed, i); end > 0 {
+                i = end - 1
+                continue
+            }
+            if trimmed[i] == '@' && isControlFlowDirective(trimmed[i:]) {
+                return true
+            }
+        }
+    }
+    if strings.Contains(trimmed, "} @") {
+        return true
+    }
+    return false
+}
+
+// expandLineWithIndent splits a line at its directives and braces. openers
+// holds the blocks open before the line, innermost last; a '}' that closes
+// one of them, and the branch that follows it on the line, takes its indent,
+// so in "<p>a</p> } @else {" the branch lines up with its @if rather than
+// with the body line it ended. When that '}' ends a body line, the branch's
+// block is of the same kind as the one it closed and body text after its
+// '{' keeps the line's own indent (lineIndent), so every branch of the chain
+// gets the same depth shift. bareClosed says the caller already peeled a '}'
+// off the start of the line that closed a block whose '{' stood alone.
+func expandLineWithIndent(trimmed, originalIndent, lineIndent string, openers []openBlock, bareClosed bool, startDepth int, indent, braceStyle string) expandResult {
+    var result []string
+    var currentLine strings.Builder
+
+    depth := startDepth
+    localDepth := 0
+    unmatched := 0
+    bareOpen := false
+    closedOuter := 0 // blocks from openers closed so far
+    opened := 0      // indented blocks opened on this line and still open
+    afterBody := false
+
+    // Text in a branch opened after a '}' that ended a body line is body
+    // text; everywhere else it is at the level of the enclosing directive
+    bodyIndent := func() string {
+        if afterBody && (opened > 0 || bareOpen) {
+            return lineIndent
+        }
+        return originalIndent
+    }
+
+    i := 0
+    for i < len(trimmed) {
+        ch := trimmed[i]
+
+        // Tags and {{ interpolation are copied verbatim so an '@' or brace
+        // inside them never splits the line or touches depth
+        if end := verbatimSpanEnd(trimmed, i); end > 0 {
+            currentLine.WriteString(trimmed[i:end])
+            i = end
+            continue
+        }
+
+        // Handle @directive
+        if ch == '@' && isControlFlowDirective(trimmed[i:]) {
+            flushWithDepth(&result, &currentLine, bodyIndent(), depth+localDepth, indent)
+            directiveIndent := bodyIndent()
+            directive, newPos := extractDirective(trimmed, i)
+            result = append(result, depthIndent(directiveIndent, depth+localDepth, indent)+directive)
+            i = newPos
+            for i < len(trimmed) && (trimmed[i] == ' ' || trimmed[i] == '\t') {
+                i++
+            }
+            if i < len(trimmed) && trimmed[i] == '{' {
+                i++
+                for i < len(trimmed) && (trimmed[i] == ' ' || trimmed[i] == '\t') {
+                    i++
+                }
+                // A branch after a '}' that closed a block whose '{' stood
+                // alone opens one like it when its body is on the lines
+                // below or continues a body line: it keeps its indentation
+                bare := bareClosed && opened == 0 && !bareOpen && (afterBody || i == len(trimmed))
+                switch {
+                case braceStyle != BraceKR && bare:
+                    result = append(result, depthIndent(directiveIndent, depth+localDepth, indent)+"{")
+                    bareOpen = true
+                case braceStyle != BraceKR:
+                    result = append(result, depthIndent(directiveIndent, depth+localDepth, indent)+"{")
+                    localDepth++
+                    opened++
+                case bare || i == len(trimmed):
+                    // "@if (a) {" ending the line: the body is already on
+                    // lines of its own and keeps their indentation, like a
+                    // block whose "{" stood alone
+                    result[len(result)-1] += " {"
+                    bareOpen = true
+                default:
+                    result[len(result)-1] += " {"
+                    localDepth++
+                    opened++
+                }
+            }
+            continue
+        }
+
+        // Handle }
+        if ch == '}' {
+            endsBody := strings.TrimSpace(currentLine.String()) != ""
+            flushWithDepth(&result, &currentLine, bodyIndent(), depth+localDepth, indent)
+            switch {
+            case opened > 0:
+                opened--
+                localDepth--
+                bareClosed = false
+            case bareOpen:
+                // Closes the block this line opened without indenting it
+                bareOpen = false
+                bareClosed = true
+            case closedOuter < len(openers):
+                closedOuter++
+                block := openers[len(openers)-closedOuter]
+                originalIndent = block.indent
+                afterBody = endsBody
+                if block.indented {
+                    localDepth--
+                } else {
+                    unmatched++
+                }
+                bareClosed = !block.indented
+            default:
+                unmatched++
+            }
+            if depth+localDepth < 0 {
+                localDepth = -depth
+                unmatched++
+            }
+            result = append(result, depthIndent(bodyIndent(), depth+localDepth, indent)+"}")
+            i++
+            for i < len(trimmed) && (trimmed[i] == ' ' || trimmed[i] == '\t') {
+                i++
+            }
+            continue
+        }
+
+        // Handle standalone {
+        if ch == '{' {
+            flushWithDepth(&result, &currentLine, bodyIndent(), depth+localDepth, indent)
+            result = append(result, depthIndent(bodyIndent(), depth+localDepth, indent)+"{")
+            localDepth++
+            opened++
+            i++
+            for i < len(trimmed) && (trimmed[i] == ' ' || trimmed[i] == '\t') {
+                i++
+            }
+            continue
+        }
+
+        currentLine.WriteByte(ch)
+        i++
+    }
+
+    flushWithDepth(&result, &currentLine, bodyIndent(), depth+localDepth, indent)
+
+    if len(result) == 0 {
+        result = []string{depthIndent(originalIndent, depth, indent) + trimmed}
+    }
+
+    return expandResult{
+        lines:      result,
+        finalDepth: depth + localDepth,
+        unmatched:  unmatched,
+        bareOpen:   bareOpen,
+        indent:     originalIndent,
+    }
+}
+
+// verbatimSpanEnd returns the end of the HTML tag or {{ }} interpolation that
+// starts at s[i], or -1 if none does. Attribute values such as
+// href="mailto:user@example.com" or bindings like [title]="'@if'" live in
+// these spans and must never be read as control flow.
+func verbatimSpanEnd(s string, i int) int {
+    if strings.HasPrefix(s[i:], "{{") {
+        return interpolationEnd(s, i+2)
+    }
+    if s[i] != '<' || i+1 >= len(s) {
+        return -1
+    }
+    next := s[i+1]
+    if !(next >= 'a' && next <= 'z' || next >= 'A' && next <= 'Z' || next == '/' || next == '!') {
+        return -1
+    }
+    // Quoted attribute values may themselves contain '>'
+    var quote byte
+    for i++; i < len(s); i++ {
+        switch ch := s[i]; {
+        case quote != 0:
+            if ch == quote {
+                quote = 0
+            }
+        case ch == '"' || ch == '\'':
+            quote = ch
+        case ch == '>':
+            return i + 1
+        }
+    }
+    return len(s)
+}
+
+// tagLeftOpen reports whether an HTML tag is still open at the end of line,
+// i.e. its attributes continue on the next line, and which attribute quote
+// is still open in that case. inTag and quote describe the state the line
+// starts in. Void and self-closing elements (<input>, <br>, <x />) close
+// on their own line like any other tag and never leave anything open.
+func tagLeftOpen(line string, inTag bool, quote byte) (bool, byte) {
+    for i := 0; i < len(line); i++ {
+        ch := line[i]
+        switch {
+        case !inTag:
+            if strings.HasPrefix(line[i:], "{{") {
+                i = interpolationEnd(line, i+2) - 1
+            } else if ch == '<' && i+1 < len(line) {
+                next := line[i+1]
+                inTag = next >= 'a' && next <= 'z' || next >= 'A' && next <= 'Z' || next == '/' || next == '!'
+            }
+        case quote != 0:
+            if ch == quote {
+                quote = 0
+            }
+        case ch == '"' || ch == '\'':
+            quote = ch
+        case ch == '>':
+            inTag = false
+        }
+    }
+    return inTag, quote
+}
+
+// opensInterpolation reports whether line starts a {{ }} interpolation that
+// is not closed on the same line.
+func opensInterpolation(line string) bool {
+    for i := 0; i+1 < len(line); i++ {
+        if line[i] != '{' || line[i+1] != '{' {
+            continue
+        }
+        end := interpolationEnd(line, i+2)
+        if end == len(line) && !strings.HasSuffix(line, "}}") {
+            return true
+        }
+        i = end - 1
+    }
+    return false
+}
+
+// interpolationEnd returns the index just past the "}}" closing the
+// interpolation whose body starts at i, or len(s) if it is unterminated.
+// Object literals and quoted strings inside the expression are skipped, so
+// "{{ {a: 1}}}" or "{{ '}}' }}" don't end early and leave a stray brace
+// behind to be counted as a block close.
+func interpolationEnd(s string, i int) int {
+    braces := 0
+    var quote byte
+    for i < len(s) {
+        ch := s[i]
+        switch {
+        case quote != 0:
+            if ch == '\\' {
+                i++
+            } else if ch == quote {
+                quote = 0
+            }
+        case ch == '\'' || ch == '"' || ch == '`':
+            quote = ch
+        case ch == '{':
+            braces++
+        case ch == '}' && braces > 0:
+            braces--
+        case ch == '}' && i+1 < len(s) && s[i+1] == '}':
+            return i + 2
+        }
+        i++
+    }
+    return len(s)
+}
+
+func depthIndent(originalIndent string, depth int, indent string) string {
+    if depth < 0 {
+        depth = 0
+    }
+    return strings.Repeat(indent, depth) + originalIndent
+}
+
+func flushWithDepth(result *[]string, currentLine *strings.Builder, originalIndent string, depth int, indent string) {
+    content := strings.TrimSpace(currentLine.String())
+    if content != "" {
+        *result = append(*result, depthIndent(originalIndent, depth, indent)+content)
+    }
+    currentLine.Reset()
+}
+
+func isControlFlowDirective(s string) bool {
+    directives := []string{"@if", "@else if", "@else", "@switch", "@case", "@default", "@for", "@empty",
+        "@defer", "@placeholder", "@loading", "@error"}
+    for _, d := range directives {
+        if strings.HasPrefix(s, d) {
+            if len(s) == len(d) {
+                return true
+            }
+            next := s[len(d)]
+            if next == ' ' || next == '(' || next == '{' || next == '\n' || next == '\t' {
+                return true
+            }
+        }
+    }
+    return false
+}
+
+// closingParen scans s with parens already open and returns the index of
+// the ')' that closes the last of them, or -1 and the number still open at
+// the end of s. Parens inside string literals don't count.
+func closingParen(s string, parens int) (int, int) {
+    var quote byte
+    for i := 0; i < len(s); i++ {
+        ch := s[i]
+        switch {
+        case quote != 0:
+            if ch == '\\' {
+                i++
+            } else if ch == quote {
+                quote = 0
+            }
+        case ch == '\'' || ch == '"' || ch == '`':
+            quote = ch
+        case ch == '(':
+            parens++
+        case ch == ')':
+            parens--
+            if parens == 0 {
+                return i, 0
+            }
+        }
+    }
+    return -1, parens
+}
+
+func extractDirective(line string, start int) (string, int) {
+    i := start
+    parenDepth := 0
+    inParens := false
+    var quote byte
+
+    for i < len(line) {
+        ch := line[i]
+        // Parens and braces inside string literals ("track byKey(')')")
+        // are part of the expression, not of the header's structure
+        if quote != 0 {
+            if ch == '\\' {
+                i++
+            } else if ch == quote {
+                quote = 0
+            }
+            i++
+            continue
+        }
+        if inParens && (ch == '\'' || ch == '"' || ch == '`') {
+            quote = ch
+        } else if ch == '(' {
+            parenDepth++
+            inParens = true
+        } else if ch == ')' {
+            parenDepth--
+            if parenDepth == 0 && inParens {
+                return line[start : i+1], i + 1
+            }
+        } else if ch == '{' && parenDepth == 0 {
+            return strings.TrimSpace(line[start:i]), i
+        }
+        i++
+    }
+    return strings.TrimSpace(line[start:]), len(line)
+}
+
+// openVerbatimTag returns "pre", "textarea", "script" or "style" if line
+// opens that element without closing it on the same line.
+func openVerbatimTag(line string) string {
+    lower := strings.ToLower(line)
+    for _, tag := range []string{"pre", "textarea", "script", "style"} {
+        open := strings.LastIndex(lower, "<"+tag)
+        if open < 0 || strings.Contains(lower[open:], "</"+tag) {
+            continue
+        }
+        rest := lower[open+len(tag)+1:]
+        if rest == "" || rest[0] == '>' || rest[0] == ' ' || rest[0] == '\t' {
+            return tag
+        }
+    }
+    return ""
+}
+
+// trailingBlanks counts the blank lines at the end of the output so far.
+func trailingBlanks(result []string) int {
+    n := 0
+    for i := len(result) - 1; i >= 0 && result[i] == ""; i-- {
+        n++
+    }
+    return n
+}
+
+func extractIndent(line string) string {
+    for i, ch := range line {
+        if ch != ' ' && ch != '\t' {
+            return line[:i]
+        }
+    }
+    return ""
+}
+
+// fileIndentUnit picks the indent unit for one template. The kind of
+// whitespace follows what the file mostly starts its lines with, so a
+// tab-indented template stays all tabs; the configured unit is used when it
+// is of that kind (or the file has no indentation). A file indented with
+// spaces under -indent tab gets its own smallest space indent instead.
+func fileIndentUnit(lines []string, configured string) string {
+    tabs, spaces, width := 0, 0, 0
+    for _, line := range lines {
+        if strings.TrimSpace(line) == "" {
+            continue
+        }
+        switch line[0] {
+        case '\t':
+            tabs++
+        case ' ':
+            spaces++
+            if n := len(line) - len(strings.TrimLeft(line, " ")); width == 0 || n < width {
+                width = n
+            }
+        }
+    }
+    switch {
+    case tabs > spaces:
+        return "\t"
+    case spaces > 0 && configured == "\t":
+        return strings.Repeat(" ", width)
+    }
+    return configured
+}
+
+// normalizeIndent rewrites the tabs in a line's leading whitespace as the
+// space unit, so stray tabs in a space-indented file don't survive next to
+// the depth indent. With a tab unit the indent is kept as it is.
+func normalizeIndent(originalIndent, unit string) string {
+    if unit == "\t" || !strings.Contains(originalIndent, "\t") {
+        return originalIndent
+    }
+    return strings.ReplaceAll(originalIndent, "\t", unit)
+}
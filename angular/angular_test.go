@@ -0,0 +1,707 @@
+package angular
+
+import (
+    "errors"
+    "fmt"
+    "strings"
+    "testing"
+)
+
+// formatCase is one template fixture: in must format to want, and want must
+// format to itself.
+type formatCase struct {
+    name     string
+    in, want string
+}
+
+func checkFormat(t *testing.T, opts Options, cases []formatCase) {
+    t.Helper()
+    for _, tc := range cases {
+        t.Run(tc.name, func(t *testing.T) {
+            got, err := FormatAngularTemplate(tc.in, opts)
+            if err != nil {
+                t.Fatalf("FormatAngularTemplate: %v", err)
+            }
+            if got != tc.want {
+                t.Fatalf("got:\n%s\nwant:\n%s", got, tc.want)
+            }
+            again, err := FormatAngularTemplate(got, opts)
+            if err != nil {
+                t.Fatalf("second pass: %v", err)
+            }
+            if again != got {
+                t.Errorf("not idempotent, second pass gave:\n%s", again)
+            }
+        })
+    }
+}
+
+func TestCRLFRoundTrip(t *testing.T) {
+    in := "<div>\r\n    @if (a) {\r\n        <p>{{ a }}</p>\r\n    } @else {\r\n        <p>b</p>\r\n    }\r\n</div>\r\n"
+    want := "<div>\r\n    @if (a)\r\n    {\r\n            <p>{{ a }}</p>\r\n    }\r\n    @else\r\n    {\r\n            <p>b</p>\r\n    }\r\n</div>\r\n"
+    checkFormat(t, DefaultOptions(), []formatCase{
+        {"crlf", in, want},
+        {"lf", strings.ReplaceAll(in, "\r\n", "\n"), strings.ReplaceAll(want, "\r\n", "\n")},
+    })
+
+    got, _ := FormatAngularTemplate(in, DefaultOptions())
+    if n := strings.Count(got, "\n"); n != strings.Count(got, "\r\n") {
+        t.Errorf("%d of %d line endings lost their \\r", n-strings.Count(got, "\r\n"), n)
+    }
+}
+
+func TestInlineBlockWithInterpolation(t *testing.T) {
+    checkFormat(t, DefaultOptions(), []formatCase{
+        {
+            "interpolation body",
+            `<div>
+    @if (x) { {{ value }} }
+    <span>after</span>
+</div>
+`,
+            `<div>
+    @if (x)
+    {
+        {{ value }}
+    }
+    <span>after</span>
+</div>
+`,
+        },
+        {
+            "two interpolations",
+            "@if (x) { {{ a }} {{ b }} }\n",
+            "@if (x)\n{\n    {{ a }} {{ b }}\n}\n",
+        },
+        {
+            "pipe and else branch",
+            "@if (x) { {{ obj | json }} } @else { {{ y }} }\n",
+            "@if (x)\n{\n    {{ obj | json }}\n}\n@else\n{\n    {{ y }}\n}\n",
+        },
+        {
+            "object literal inside the interpolation",
+            "@if (x) { {{ { a: 1 }.a }} }\n",
+            "@if (x)\n{\n    {{ { a: 1 }.a }}\n}\n",
+        },
+    })
+}
+
+func TestDeferBlocks(t *testing.T) {
+    checkFormat(t, DefaultOptions(), []formatCase{
+        {
+            "defer with placeholder, loading and error",
+            `<section>
+    @defer (on viewport) {
+        <app-chart [data]="data" />
+    } @placeholder (minimum 500ms) {
+        <p>Chart placeholder</p>
+    } @loading (after 100ms; minimum 1s) {
+        <app-spinner />
+    } @error {
+        <p>Could not load the chart.</p>
+    }
+</section>
+`,
+            `<section>
+    @defer (on viewport)
+    {
+            <app-chart [data]="data" />
+    }
+    @placeholder (minimum 500ms)
+    {
+            <p>Chart placeholder</p>
+    }
+    @loading (after 100ms; minimum 1s)
+    {
+            <app-spinner />
+    }
+    @error
+    {
+            <p>Could not load the chart.</p>
+    }
+</section>
+`,
+        },
+        {
+            "trigger with nested parens on one line",
+            "@defer (on timer(5s)) { <app-a /> } @placeholder { <p>x</p> }\n",
+            "@defer (on timer(5s))\n{\n    <app-a />\n}\n@placeholder\n{\n    <p>x</p>\n}\n",
+        },
+    })
+}
+
+func TestVerbatimElements(t *testing.T) {
+    checkFormat(t, DefaultOptions(), []formatCase{
+        {
+            "pre and textarea inside a block",
+            `<div>
+    @if (show) {
+        <pre>
+  indented   line
+@if (not a block) { x }
+
+    last line</pre
+        >
+        <textarea>
+   keep
+      this</textarea>
+    }
+</div>
+`,
+            `<div>
+    @if (show)
+    {
+            <pre>
+  indented   line
+@if (not a block) { x }
+
+    last line</pre
+            >
+            <textarea>
+   keep
+      this</textarea>
+    }
+</div>
+`,
+        },
+        {
+            "inline style and script in index.html",
+            `<!doctype html>
+<html lang="en">
+  <head>
+    <style>
+      body {
+        margin: 0;
+      }
+    </style>
+    <style>body {
+  margin: 0;
+}</style>
+  </head>
+  <body>
+    <app-root></app-root>
+    <script>function init() {
+  if (window.ready) {
+    start();
+  }
+}</script>
+  </body>
+</html>
+`,
+            `<!doctype html>
+<html lang="en">
+  <head>
+    <style>
+      body {
+        margin: 0;
+      }
+    </style>
+    <style>body {
+  margin: 0;
+}</style>
+  </head>
+  <body>
+    <app-root></app-root>
+    <script>function init() {
+  if (window.ready) {
+    start();
+  }
+}</script>
+  </body>
+</html>
+`,
+        },
+        {
+            "script inside a block",
+            `@if (analytics) {
+    <script type="module">
+        const config = { id: 1 };
+
+
+        track(config);
+    </script>
+}
+`,
+            `@if (analytics)
+{
+        <script type="module">
+        const config = { id: 1 };
+
+
+        track(config);
+    </script>
+}
+`,
+        },
+    })
+}
+
+func TestSwitchCases(t *testing.T) {
+    checkFormat(t, DefaultOptions(), []formatCase{
+        {
+            "multi-case switch",
+            `<div>
+    @switch (x) {
+        @case ('a') {
+            <p>a</p>
+        }
+        @case ('b') {
+            <p>b</p>
+        }
+        @default {
+            <p>other</p>
+        }
+    }
+</div>
+`,
+            `<div>
+    @switch (x)
+    {
+            @case ('a')
+            {
+                    <p>a</p>
+            }
+            @case ('b')
+            {
+                    <p>b</p>
+            }
+            @default
+            {
+                    <p>other</p>
+            }
+    }
+</div>
+`,
+        },
+        {
+            "switch on one line",
+            "@switch (x) { @case ('a') { <p>a</p> } @default { <p>d</p> } }\n",
+            `@switch (x)
+{
+    @case ('a')
+    {
+        <p>a</p>
+    }
+    @default
+    {
+        <p>d</p>
+    }
+}
+`,
+        },
+    })
+}
+
+func TestAtSignsInAttributesAndStrings(t *testing.T) {
+    checkFormat(t, DefaultOptions(), []formatCase{
+        {
+            "attributes, bindings and interpolation",
+            `<div>
+    <a href="mailto:user@example.com">user@example.com</a>
+    <input [title]="'@if (x) {'" placeholder='@for { }' />
+    <p>{{ '@else {' + name }}</p>
+    @if (a) {
+        <span data-at="@switch">x</span>
+    }
+</div>
+`,
+            `<div>
+    <a href="mailto:user@example.com">user@example.com</a>
+    <input [title]="'@if (x) {'" placeholder='@for { }' />
+    <p>{{ '@else {' + name }}</p>
+    @if (a)
+    {
+            <span data-at="@switch">x</span>
+    }
+</div>
+`,
+        },
+        {
+            "inside a one-line block",
+            `@if (a) { <a href="mailto:x@y.z">{{ "@if" }}</a> }` + "\n",
+            "@if (a)\n{\n    <a href=\"mailto:x@y.z\">{{ \"@if\" }}</a>\n}\n",
+        },
+    })
+}
+
+func TestMultiLineInterpolation(t *testing.T) {
+    checkFormat(t, DefaultOptions(), []formatCase{
+        {
+            "pipe continuation lines are kept as written",
+            `<div>
+    @if (items) {
+        <p>
+            {{ someVeryLongExpression
+              | pipe1: arg
+              | pipe2 }}
+        </p>
+    }
+    <span
+        >{{ total
+          | currency }}</span
+    >
+</div>
+`,
+            `<div>
+    @if (items)
+    {
+            <p>
+                {{ someVeryLongExpression
+              | pipe1: arg
+              | pipe2 }}
+            </p>
+    }
+    <span
+        >{{ total
+          | currency }}</span
+    >
+</div>
+`,
+        },
+    })
+}
+
+func TestWrappedTagAttributes(t *testing.T) {
+    checkFormat(t, DefaultOptions(), []formatCase{
+        {
+            "wrapped tag before a block",
+            `<div
+    class="row"
+    [ngClass]="{
+        active: a,
+    }"
+>
+    @if (a) {
+        <button
+            type="button"
+            (click)="go({ id: 1 })"
+        >
+            Go
+        </button>
+    }
+</div>
+`,
+            `<div
+    class="row"
+    [ngClass]="{
+        active: a,
+    }"
+>
+    @if (a)
+    {
+            <button
+                type="button"
+                (click)="go({ id: 1 })"
+            >
+                Go
+            </button>
+    }
+</div>
+`,
+        },
+        {
+            "wrapped tag without braces",
+            `<div>
+    <img
+        src="a.png"
+        alt="{{ name }}"
+    />
+    <p>a</p>
+</div>
+`,
+            `<div>
+    <img
+        src="a.png"
+        alt="{{ name }}"
+    />
+    <p>a</p>
+</div>
+`,
+        },
+    })
+}
+
+func TestUnbalancedBraces(t *testing.T) {
+    tests := []struct {
+        name string
+        in   string
+        line int
+    }{
+        {"extra brace on its own line", "<div>\n    @if (a) {\n        <p>a</p>\n    }\n    }\n    <p>after</p>\n</div>\n", 5},
+        {"extra brace on a one-line block", "@if (a) { <p>a</p> } }\n", 1},
+        {"extra brace after a formatted block", "@if (a)\n{\n    <p>a</p>\n}\n}\n", 5},
+        {"stray brace before any block", "}\n@if (a) { <p>a</p> }\n", 1},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            out, err := FormatAngularTemplate(tt.in, DefaultOptions())
+            var unbalanced *UnbalancedBraceError
+            if !errors.As(err, &unbalanced) {
+                t.Fatalf("got %q, %v; want an *UnbalancedBraceError", out, err)
+            }
+            if unbalanced.Line != tt.line {
+                t.Errorf("error on line %d, want %d", unbalanced.Line, tt.line)
+            }
+        })
+    }
+
+    // Braces already on their own line, from an earlier run, are balanced
+    checkFormat(t, DefaultOptions(), []formatCase{
+        {
+            "previously formatted",
+            "<div>\n    @if (a)\n    {\n        <p>a</p>\n    }\n    @else\n    {\n        <p>b</p>\n    }\n</div>\n",
+            "<div>\n    @if (a)\n    {\n        <p>a</p>\n    }\n    @else\n    {\n        <p>b</p>\n    }\n</div>\n",
+        },
+        {
+            "formatted block followed by an inline else",
+            "@if (a)\n{\n    <p>a</p>\n} @else {\n    <p>b</p>\n}\n",
+            "@if (a)\n{\n    <p>a</p>\n}\n@else\n{\n    <p>b</p>\n}\n",
+        },
+        {"brace inside text", "<p>}</p>\n{{ a }}\n", "<p>}</p>\n{{ a }}\n"},
+    })
+}
+
+func TestFinalNewline(t *testing.T) {
+    keep := DefaultOptions()
+    keep.KeepMissingNewline = true
+    t.Run("added by default", func(t *testing.T) {
+        checkFormat(t, DefaultOptions(), []formatCase{
+            {"missing", "@if (a) { <p>a</p> }", "@if (a)\n{\n    <p>a</p>\n}\n"},
+            {"present", "@if (a) { <p>a</p> }\n", "@if (a)\n{\n    <p>a</p>\n}\n"},
+            {"crlf", "<p>a</p>\r\n<p>b</p>", "<p>a</p>\r\n<p>b</p>\r\n"},
+            {"empty file", "", ""},
+        })
+    })
+    t.Run("kept missing", func(t *testing.T) {
+        checkFormat(t, keep, []formatCase{
+            {"missing", "@if (a) { <p>a</p> }", "@if (a)\n{\n    <p>a</p>\n}"},
+            {"present", "@if (a) { <p>a</p> }\n", "@if (a)\n{\n    <p>a</p>\n}\n"},
+            {"trailing blank line within the limit", "<p>a</p>\n\n", "<p>a</p>\n\n"},
+        })
+    })
+}
+
+func TestMaxBlankLines(t *testing.T) {
+    in := "<div>\n\n\n    <p>a</p>\n\n\n\n    <p>b</p>\n\n    <p>c</p>\n</div>\n"
+    for _, tt := range []struct {
+        max  int
+        want string
+    }{
+        {1, "<div>\n\n    <p>a</p>\n\n    <p>b</p>\n\n    <p>c</p>\n</div>\n"},
+        {2, "<div>\n\n\n    <p>a</p>\n\n\n    <p>b</p>\n\n    <p>c</p>\n</div>\n"},
+        {0, "<div>\n\n    <p>a</p>\n\n    <p>b</p>\n\n    <p>c</p>\n</div>\n"},
+        {-1, in},
+    } {
+        opts := DefaultOptions()
+        opts.MaxBlankLines = tt.max
+        t.Run(fmt.Sprintf("max %d", tt.max), func(t *testing.T) {
+            checkFormat(t, opts, []formatCase{{"runs above and below the limit", in, tt.want}})
+        })
+    }
+
+    opts := DefaultOptions()
+    opts.NoBlankLines = true
+    checkFormat(t, opts, []formatCase{
+        {"no blank lines", in, "<div>\n    <p>a</p>\n    <p>b</p>\n    <p>c</p>\n</div>\n"},
+    })
+
+    // Blank lines that are part of the content are never collapsed
+    checkFormat(t, opts, []formatCase{
+        {"pre", "<pre>\nx\n\n\n\ny\n</pre>\n", "<pre>\nx\n\n\n\ny\n</pre>\n"},
+        {"comment", "<!--\nc\n\n\nd\n-->\n", "<!--\nc\n\n\nd\n-->\n"},
+        {"string in an interpolation", "<p>{{ 'e\n\n\nf' }}</p>\n", "<p>{{ 'e\n\n\nf' }}</p>\n"},
+    })
+}
+
+// The zero Options is usable as is and formats like DefaultOptions.
+func TestZeroOptions(t *testing.T) {
+    in := "<div>\n\n\n    @if (a) { <p>a</p> }\n</div>"
+    want, err := FormatAngularTemplate(in, DefaultOptions())
+    if err != nil {
+        t.Fatal(err)
+    }
+    checkFormat(t, Options{}, []formatCase{{"as DefaultOptions", in, want}})
+    if want != "<div>\n\n    @if (a)\n    {\n        <p>a</p>\n    }\n</div>\n" {
+        t.Errorf("DefaultOptions gave:\n%s", want)
+    }
+}
+
+func TestBraceStyleKR(t *testing.T) {
+    opts := DefaultOptions()
+    opts.BraceStyle = BraceKR
+    checkFormat(t, opts, []formatCase{
+        {
+            "if else chain",
+            `<div>
+    @if (a) {
+        <p>a</p>
+    } @else if (b) {
+        <p>b</p>
+    }
+    @else
+    {
+        <p>c</p>
+    }
+</div>
+`,
+            `<div>
+    @if (a) {
+        <p>a</p>
+    }
+    @else if (b) {
+        <p>b</p>
+    }
+    @else {
+        <p>c</p>
+    }
+</div>
+`,
+        },
+        {
+            "for with empty on one line",
+            `<ul>
+    @for (item of items; track item.id) { <li>{{ item }}</li> } @empty { <li>none</li> }
+</ul>
+`,
+            `<ul>
+    @for (item of items; track item.id) {
+        <li>{{ item }}</li>
+    }
+    @empty {
+        <li>none</li>
+    }
+</ul>
+`,
+        },
+        {
+            "allman input nested",
+            `@if (user.admin)
+{
+    <app-admin />
+    @for (x of xs; track x)
+    {
+        <p>{{ x }}</p>
+    }
+} @else { <app-guest /> }
+`,
+            `@if (user.admin) {
+    <app-admin />
+    @for (x of xs; track x) {
+        <p>{{ x }}</p>
+    }
+}
+@else {
+    <app-guest />
+}
+`,
+        },
+    })
+}
+
+func TestForHeaders(t *testing.T) {
+    checkFormat(t, DefaultOptions(), []formatCase{
+        {
+            "track call and let aliases",
+            `<ul>
+    @for (item of items; track trackById($index, item); let i = $index, last = $last, even = $even) { <li>{{ i }} {{ item }}</li> }
+</ul>
+`,
+            `<ul>
+    @for (item of items; track trackById($index, item); let i = $index, last = $last, even = $even)
+    {
+        <li>{{ i }} {{ item }}</li>
+    }
+</ul>
+`,
+        },
+        {
+            "track fn with an object literal",
+            "@for (x of xs; track identify(x, { deep: true })) { <p>{{ x }}</p> }\n",
+            "@for (x of xs; track identify(x, { deep: true }))\n{\n    <p>{{ x }}</p>\n}\n",
+        },
+        {
+            "separate let clauses",
+            "@for (item of items; track fn(item); let i = $index; let odd = $odd) { <p>{{ i }}</p> } @empty { <p>none</p> }\n",
+            "@for (item of items; track fn(item); let i = $index; let odd = $odd)\n{\n    <p>{{ i }}</p>\n}\n@empty\n{\n    <p>none</p>\n}\n",
+        },
+    })
+}
+
+func TestTabIndentation(t *testing.T) {
+    tabFile := "<div>\n\t@if (a) { <p>a</p> } @else {\n\t\t<p>b</p>\n\t}\n</div>\n"
+    tabWant := "<div>\n\t@if (a)\n\t{\n\t\t<p>a</p>\n\t}\n\t@else\n\t{\n\t\t\t<p>b</p>\n\t}\n</div>\n"
+    spaceFile := strings.ReplaceAll(tabFile, "\t", "    ")
+    spaceWant := strings.ReplaceAll(tabWant, "\t", "    ")
+
+    tabs := DefaultOptions()
+    tabs.Indent = "\t"
+    for _, tt := range []struct {
+        name  string
+        opts  Options
+        cases []formatCase
+    }{
+        {"space unit", DefaultOptions(), []formatCase{{"tab file", tabFile, tabWant}, {"space file", spaceFile, spaceWant}}},
+        {"tab unit", tabs, []formatCase{{"tab file", tabFile, tabWant}, {"space file", spaceFile, spaceWant}}},
+    } {
+        t.Run(tt.name, func(t *testing.T) {
+            checkFormat(t, tt.opts, tt.cases)
+            for _, tc := range tt.cases {
+                got, _ := FormatAngularTemplate(tc.in, tt.opts)
+                for _, line := range strings.Split(got, "\n") {
+                    lead := line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+                    if strings.Contains(lead, " ") && strings.Contains(lead, "\t") {
+                        t.Errorf("%s: mixed indentation in %q", tc.name, line)
+                    }
+                }
+            }
+        })
+    }
+}
+
+func TestBraceEndingBodyLine(t *testing.T) {
+    in := `<div>
+    @if (a) {
+        <p>a</p> } @else if (b) {
+        <p>b</p> } @else { <p>c</p>
+    }
+</div>
+`
+    checkFormat(t, DefaultOptions(), []formatCase{{
+        "allman",
+        in,
+        `<div>
+    @if (a)
+    {
+            <p>a</p>
+    }
+    @else if (b)
+    {
+            <p>b</p>
+    }
+    @else
+    {
+            <p>c</p>
+    }
+</div>
+`,
+    }})
+
+    kr := DefaultOptions()
+    kr.BraceStyle = BraceKR
+    checkFormat(t, kr, []formatCase{{
+        "k&r",
+        in,
+        `<div>
+    @if (a) {
+        <p>a</p>
+    }
+    @else if (b) {
+        <p>b</p>
+    }
+    @else {
+        <p>c</p>
+    }
+</div>
+`,
+    }})
+}
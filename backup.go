@@ -0,0 +1,174 @@
+package main
+
+import (
+    "crypto/sha256"
+    "encoding/hex"
+    "errors"
+    "fmt"
+    "io/fs"
+    "os"
+    "path/filepath"
+    "strings"
+)
+
+// --- BACKUPS ---
+
+var (
+    // backupFiles copies every HTML file aside before the pipeline rewrites it (-backup)
+    backupFiles bool
+    // restoreBackups puts the backed up files back and exits (-restore)
+    restoreBackups bool
+    // keepBackups leaves the backups in place after a successful run (-keep-backups)
+    keepBackups bool
+)
+
+// backupsTaken is set once this run has written a backup.
+var backupsTaken bool
+
+// backupDir is where the backups of repoPath live: one directory per
+// repository under the user's cache dir, mirroring the files' repo-relative
+// paths. Keeping them out of the work tree means git never sees them, and
+// out of the shared temp dir means other users can neither read nor plant
+// them. Without a cache dir they go to the per-user fallback tool home.
+func backupDir() string {
+    base, err := os.UserCacheDir()
+    if err != nil {
+        base = fallbackToolHome()
+    }
+    sum := sha256.Sum256([]byte(repoPath))
+    return filepath.Join(base, "go-formatter", "backups", hex.EncodeToString(sum[:])[:16])
+}
+
+// checkBackupDir refuses a backup directory that another user created (or
+// replaced with a symlink): its files would be written into the repository.
+func checkBackupDir(dir string) error {
+    info, err := os.Lstat(dir)
+    if err != nil {
+        return err
+    }
+    if !info.IsDir() || !ownedByCurrentUser(info) {
+        return fmt.Errorf("%s is not a directory owned by you", dir)
+    }
+    return nil
+}
+
+// backupOriginals saves the current content of files before they are
+// formatted. The first backup a run takes clears those left by an earlier
+// run, and a file is only saved once, so -restore always returns to the
+// state before this run. Dry runs write nothing and need none.
+func backupOriginals(files []string) {
+    if !backupFiles || dryRun {
+        return
+    }
+    assertWritable("back up files")
+    dir := backupDir()
+    if !backupsTaken {
+        if err := os.RemoveAll(dir); err != nil {
+            fatalf("Error clearing old backups in %s: %v", dir, err)
+        }
+        backupsTaken = true
+    }
+    for _, file := range files {
+        rel, err := filepath.Rel(repoPath, file)
+        if err != nil || strings.HasPrefix(rel, "..") {
+            warnf("%s\n", yellow("Warning: not backing up "+file+": outside -path."))
+            continue
+        }
+        target := filepath.Join(dir, rel)
+        if _, err := os.Stat(target); err == nil {
+            continue
+        }
+        content, err := os.ReadFile(file)
+        if errors.Is(err, fs.ErrNotExist) {
+            continue
+        }
+        if err == nil {
+            err = os.MkdirAll(filepath.Dir(target), 0700)
+        }
+        if err == nil {
+            err = checkBackupDir(dir)
+        }
+        if err == nil {
+            err = writeFile(target, content, 0600)
+        }
+        if err != nil {
+            // Without a copy there is no safety net; don't format anything
+            fatalf("Error backing up %s: %v", relPath(file), err)
+        }
+        verbosef("Backed up %s to %s", relPath(file), target)
+    }
+}
+
+// finishBackups removes this run's backups if it succeeded, unless
+// -keep-backups is set, and otherwise says where they are.
+func finishBackups() {
+    if !backupsTaken {
+        return
+    }
+    dir := backupDir()
+    if exitStatus == 0 && !keepBackups {
+        os.RemoveAll(dir)
+        return
+    }
+    logf("Backups of the original HTML files are in %s; run with -restore to put them back.\n", dir)
+}
+
+// runRestore copies every backup of repoPath back over the formatted file,
+// then removes the backups unless -keep-backups is set. With -dry-run it
+// only lists what it would restore.
+func runRestore() {
+    dir := backupDir()
+    if _, err := os.Lstat(dir); err != nil {
+        logf("No backups to restore for %s.\n", repoPath)
+        return
+    }
+    if err := checkBackupDir(dir); err != nil {
+        fatalf("Refusing to restore backups: %v", err)
+    }
+
+    if !dryRun {
+        assertWritable("restore backups")
+    }
+    restored := 0
+    err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+        if err != nil || d.IsDir() {
+            return err
+        }
+        rel, _ := filepath.Rel(dir, path)
+        target := filepath.Join(repoPath, rel)
+        if dryRun {
+            logf("Would restore %s\n", filepath.ToSlash(rel))
+            restored++
+            return nil
+        }
+        content, err := os.ReadFile(path)
+        if err != nil {
+            return err
+        }
+        mode := fs.FileMode(0644)
+        if info, err := os.Stat(target); err == nil {
+            mode = info.Mode().Perm()
+        }
+        if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+            return err
+        }
+        if err := writeFile(target, content, mode); err != nil {
+            return err
+        }
+        logf("Restored %s\n", filepath.ToSlash(rel))
+        restored++
+        return nil
+    })
+    if err != nil {
+        fatalf("Error restoring backups from %s: %v", dir, err)
+    }
+
+    if dryRun {
+        logf("%d file(s) would be restored.\n", restored)
+        return
+    }
+    logln(green(fmt.Sprintf("%d file(s) restored.", restored)))
+    if !keepBackups {
+        os.RemoveAll(dir)
+    }
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+    "os"
+    "path/filepath"
+    "runtime"
+    "strings"
+    "testing"
+)
+
+func TestBackupsArePrivate(t *testing.T) {
+    if runtime.GOOS == "windows" {
+        t.Skip("permission bits are not enforced on Windows")
+    }
+    cache := t.TempDir()
+    t.Setenv("XDG_CACHE_HOME", cache)
+    t.Setenv("HOME", cache)
+    savedRepo, savedBackup, savedTaken := repoPath, backupFiles, backupsTaken
+    repoPath, backupFiles, backupsTaken = t.TempDir(), true, false
+    t.Cleanup(func() { repoPath, backupFiles, backupsTaken = savedRepo, savedBackup, savedTaken })
+
+    file := filepath.Join(repoPath, "src", "a.component.html")
+    if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+        t.Fatal(err)
+    }
+    if err := os.WriteFile(file, []byte("<p>a</p>\n"), 0644); err != nil {
+        t.Fatal(err)
+    }
+    backupOriginals([]string{file})
+
+    dir := backupDir()
+    if !strings.HasPrefix(dir, cache) {
+        t.Errorf("backups in %s, want them under the user cache dir %s", dir, cache)
+    }
+    for path, want := range map[string]os.FileMode{
+        dir:                                           0700,
+        filepath.Join(dir, "src"):                     0700,
+        filepath.Join(dir, "src", "a.component.html"): 0600,
+    } {
+        info, err := os.Stat(path)
+        if err != nil {
+            t.Fatal(err)
+        }
+        if got := info.Mode().Perm(); got != want {
+            t.Errorf("%s has mode %o, want %o", path, got, want)
+        }
+    }
+}
+
+func TestCheckBackupDirRefusesSymlink(t *testing.T) {
+    root := t.TempDir()
+    planted := filepath.Join(root, "planted")
+    if err := os.Mkdir(planted, 0700); err != nil {
+        t.Fatal(err)
+    }
+    link := filepath.Join(root, "backups")
+    if err := os.Symlink(planted, link); err != nil {
+        t.Skip("symlinks not supported:", err)
+    }
+    if err := checkBackupDir(link); err == nil {
+        t.Error("a symlinked backup directory was accepted")
+    }
+    if err := checkBackupDir(planted); err != nil {
+        t.Errorf("own backup directory refused: %v", err)
+    }
+}
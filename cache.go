@@ -0,0 +1,188 @@
+package main
+
+import (
+    "crypto/sha256"
+    "encoding/hex"
+    "encoding/json"
+    "fmt"
+    "io"
+    "io/fs"
+    "os"
+    "path/filepath"
+    "strings"
+)
+
+// --- FORMAT CACHE ---
+
+// The cache remembers the content hash of every file after it was formatted
+// successfully, so unchanged files can skip ESLint/Prettier on the next run.
+// It is stored in toolHome and reset whenever the configs change.
+
+const cacheFileName = "cache.json"
+
+// noCache disables reading and writing the cache (-no-cache).
+var noCache bool
+
+type formatCache struct {
+    ConfigHash string            `json:"configHash"`
+    Files      map[string]string `json:"files"` // absolute path -> sha256 of formatted content
+}
+
+// loadCache reads the cache, discarding it if it was built with other configs.
+// It returns nil when caching is disabled.
+func loadCache() *formatCache {
+    if noCache {
+        return nil
+    }
+    current := configHash()
+    cache := &formatCache{ConfigHash: current, Files: map[string]string{}}
+
+    content, err := os.ReadFile(filepath.Join(toolHome, cacheFileName))
+    if err != nil {
+        return cache
+    }
+    var stored formatCache
+    if err := json.Unmarshal(content, &stored); err != nil || stored.ConfigHash != current || stored.Files == nil {
+        return cache
+    }
+    return &stored
+}
+
+// fresh reports whether path still has the content it had after its last
+// successful format.
+func (c *formatCache) fresh(path string) bool {
+    if c == nil {
+        return false
+    }
+    stored, ok := c.Files[path]
+    if !ok {
+        return false
+    }
+    hash, err := hashFile(path)
+    return err == nil && hash == stored
+}
+
+// update stores the current hash of path, or forgets it if it can't be read.
+func (c *formatCache) update(path string) {
+    if c == nil {
+        return
+    }
+    hash, err := hashFile(path)
+    if err != nil {
+        delete(c.Files, path)
+        return
+    }
+    c.Files[path] = hash
+}
+
+// record updates the cache for the files of a finished run. Files that
+// failed are forgotten, and so are files with warnings left while
+// -max-warnings is set: skipping them next time would drop their warnings
+// from the total the limit is checked against.
+func (c *formatCache) record(files []string) {
+    unclean := make(map[string]bool)
+    for _, r := range results {
+        if r.failed() || (maxWarnings >= 0 && r.warnings > 0) {
+            unclean[r.path] = true
+        }
+    }
+    for _, f := range files {
+        if unclean[f] {
+            c.forget(f)
+        } else {
+            c.update(f)
+        }
+    }
+}
+
+func (c *formatCache) forget(path string) {
+    if c != nil {
+        delete(c.Files, path)
+    }
+}
+
+func (c *formatCache) save() {
+    // -no-install: toolHome is provisioned by someone else and stays untouched
+    if c == nil || noInstall {
+        return
+    }
+    content, err := json.MarshalIndent(c, "", "  ")
+    if err != nil {
+        return
+    }
+    if err := writeFile(filepath.Join(toolHome, cacheFileName), content, 0644); err != nil {
+        warnf("Warning: could not save format cache: %v\n", err)
+    }
+}
+
+func hashFile(path string) (string, error) {
+    content, err := os.ReadFile(path)
+    if err != nil {
+        return "", err
+    }
+    sum := sha256.Sum256(content)
+    return hex.EncodeToString(sum[:]), nil
+}
+
+// configHash fingerprints everything that influences formatting output: the
+// embedded configs, any user-supplied config overrides and the settings of
+// the custom HTML pass.
+func configHash() string {
+    h := sha256.New()
+    hashEmbeddedConfigs(h)
+    overrides := []string{eslintConfig, prettierConfig}
+    if useLocalTools {
+        overrides = append(overrides, localToolFiles...)
+    }
+    for _, override := range overrides {
+        if override == "" {
+            continue
+        }
+        content, _ := os.ReadFile(resolveRepoPath(override))
+        fmt.Fprintf(h, "%s\x00%d\x00", override, len(content))
+        h.Write(content)
+    }
+    // A file formatted with another -indent is not formatted for this one
+    fmt.Fprintf(h, "angular\x00%q\x00%s\x00%d\x00%t\x00", indentUnit, braceStyle, maxBlankLines, finalNewline)
+    // ...and one formatted by Prettier alone has not had the brace pass
+    if noCustomHtml {
+        fmt.Fprint(h, "no-custom-html\x00")
+    }
+    // ...or has since been listed in (or dropped from) .angularformatignore
+    optOut, _ := os.ReadFile(filepath.Join(repoPath, angularIgnoreFileName))
+    fmt.Fprintf(h, "%s\x00%d\x00", angularIgnoreFileName, len(optOut))
+    h.Write(optOut)
+    // A new team post-processor has not seen the cached files either
+    for _, p := range postProcessors {
+        fmt.Fprintf(h, "post-processor\x00%s\x00", p.name)
+    }
+    // Files cached without the import pass still need it
+    if sortImports {
+        fmt.Fprintf(h, "sort-imports\x00%s\x00", strings.Join(internalImports, "\x00"))
+    }
+    return hex.EncodeToString(h.Sum(nil))
+}
+
+// hashEmbeddedConfigs writes every embedded config, with its path, to h.
+func hashEmbeddedConfigs(h io.Writer) {
+    fs.WalkDir(configFiles, ".", func(path string, d fs.DirEntry, err error) error {
+        if err != nil || d.IsDir() {
+            return nil
+        }
+        content, _ := configFiles.ReadFile(path)
+        fmt.Fprintf(h, "%s\x00%d\x00", path, len(content))
+        h.Write(content)
+        return nil
+    })
+}
+
+// localToolFiles are the repo-relative files that decide the output of the
+// project's own toolchain (-prefer-local): the installed versions and the
+// config files ESLint and Prettier pick up from the repo root.
+var localToolFiles = []string{
+    "node_modules/eslint/package.json", "node_modules/prettier/package.json", "package.json",
+    "eslint.config.js", "eslint.config.mjs", "eslint.config.cjs", "eslint.config.ts",
+    ".prettierrc", ".prettierrc.json", ".prettierrc.yaml", ".prettierrc.yml",
+    ".prettierrc.js", ".prettierrc.mjs", ".prettierrc.cjs", "prettier.config.js", "prettier.config.mjs",
+    "prettier.config.cjs", ".prettierignore",
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+    "os"
+    "path/filepath"
+    "slices"
+    "testing"
+)
+
+// TestConfigHashCoversSettings checks that every setting that changes what a
+// pass writes also changes the cache key, so a file formatted under the old
+// value is not skipped as unchanged.
+func TestConfigHashCoversSettings(t *testing.T) {
+    savedRepo := repoPath
+    repoPath = t.TempDir()
+    t.Cleanup(func() { repoPath = savedRepo })
+
+    tests := []struct {
+        name string
+        set  func() (restore func())
+    }{
+        {"-indent", func() func() {
+            saved := indentUnit
+            indentUnit = "  "
+            return func() { indentUnit = saved }
+        }},
+        {"-indent tab", func() func() {
+            saved := indentUnit
+            indentUnit = "\t"
+            return func() { indentUnit = saved }
+        }},
+        {"-final-newline=false", func() func() {
+            saved := finalNewline
+            finalNewline = !saved
+            return func() { finalNewline = saved }
+        }},
+        {"-brace-style", func() func() {
+            saved := braceStyle
+            braceStyle = braceKR
+            return func() { braceStyle = saved }
+        }},
+        {"-max-blank-lines", func() func() {
+            saved := maxBlankLines
+            maxBlankLines = 3
+            return func() { maxBlankLines = saved }
+        }},
+        {"-no-custom-html", func() func() {
+            saved := noCustomHtml
+            noCustomHtml = !saved
+            return func() { noCustomHtml = saved }
+        }},
+        {angularIgnoreFileName, func() func() {
+            path := filepath.Join(repoPath, angularIgnoreFileName)
+            os.WriteFile(path, []byte("src/legacy/**\n"), 0644)
+            return func() { os.Remove(path) }
+        }},
+        {"a registered post-processor", func() func() {
+            saved := postProcessors
+            postProcessors = slices.Clone(saved)
+            registerPostProcessor("tailwind", func(path, content string) (string, error) { return content, nil })
+            return func() { postProcessors = saved }
+        }},
+    }
+    base := configHash()
+    for _, tt := range tests {
+        restore := tt.set()
+        if configHash() == base {
+            t.Errorf("%s does not change the cache key", tt.name)
+        }
+        restore()
+        if configHash() != base {
+            t.Fatalf("restoring %s did not restore the cache key", tt.name)
+        }
+    }
+}
+
+func TestCacheRecordWarnings(t *testing.T) {
+    dir := t.TempDir()
+    clean, warned, failing := filepath.Join(dir, "clean.ts"), filepath.Join(dir, "warned.ts"), filepath.Join(dir, "failing.ts")
+    for _, f := range []string{clean, warned, failing} {
+        if err := os.WriteFile(f, []byte("export const a = 1;\n"), 0644); err != nil {
+            t.Fatal(err)
+        }
+    }
+    savedMax := maxWarnings
+    t.Cleanup(func() { maxWarnings = savedMax; resetResults() })
+
+    for _, tt := range []struct {
+        name        string
+        maxWarnings int
+        wantWarned  bool
+    }{
+        {"no limit caches warnings", -1, true},
+        {"-max-warnings leaves them out", 0, false},
+        {"-max-warnings 10 leaves them out", 10, false},
+    } {
+        t.Run(tt.name, func(t *testing.T) {
+            maxWarnings = tt.maxWarnings
+            resetResults()
+            recordResult(fileResult{path: clean, tool: "eslint"})
+            recordResult(fileResult{path: warned, tool: "eslint", warnings: 2})
+            recordResult(fileResult{path: failing, tool: "eslint", errors: 1})
+
+            cache := &formatCache{Files: map[string]string{}}
+            cache.record([]string{clean, warned, failing})
+            if !cache.fresh(clean) {
+                t.Error("clean file was not cached")
+            }
+            if cache.fresh(failing) {
+                t.Error("file with errors was cached")
+            }
+            if got := cache.fresh(warned); got != tt.wantWarned {
+                t.Errorf("file with warnings cached = %t, want %t", got, tt.wantWarned)
+            }
+        })
+    }
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+    "io"
+    "os"
+)
+
+// --- COLORS ---
+
+// useColor turns on color for the tool's own status lines. It is decided once
+// in main with colorSupported, after logOut is known.
+var useColor bool
+
+const (
+    ansiRed    = "\x1b[31m"
+    ansiGreen  = "\x1b[32m"
+    ansiYellow = "\x1b[33m"
+    ansiReset  = "\x1b[0m"
+)
+
+// colorSupported reports whether w is a terminal that should get color.
+// NO_COLOR (https://no-color.org) and TERM=dumb always turn it off.
+func colorSupported(w io.Writer) bool {
+    if os.Getenv("NO_COLOR") != "" || os.Getenv("TERM") == "dumb" {
+        return false
+    }
+    f, ok := w.(*os.File)
+    if !ok {
+        return false
+    }
+    info, err := f.Stat()
+    return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+func paint(code, s string) string {
+    if !useColor {
+        return s
+    }
+    return code + s + ansiReset
+}
+
+// green marks success, red remaining errors and failures, yellow warnings
+// and files that changed.
+func green(s string) string  { return paint(ansiGreen, s) }
+func red(s string) string    { return paint(ansiRed, s) }
+func yellow(s string) string { return paint(ansiYellow, s) }
@@ -0,0 +1,122 @@
+package main
+
+import (
+    "encoding/json"
+    "flag"
+    "os"
+    "path/filepath"
+    "strings"
+
+    "gopkg.in/yaml.v3"
+)
+
+// --- CONFIG FILE ---
+
+// configFileNames are looked up in repoPath and each parent up to the git root.
+var configFileNames = []string{".go-formatter.yaml", ".go-formatter.yml", ".go-formatter.json"}
+
+// fileConfig holds the settings a team can commit to the repo instead of
+// passing flags. Empty fields leave the flag default alone.
+type fileConfig struct {
+    Indent          string            `json:"indent" yaml:"indent"`
+    BraceStyle      string            `json:"braceStyle" yaml:"braceStyle"`
+    PackageManager  string            `json:"packageManager" yaml:"packageManager"`
+    SkipGlobs       []string          `json:"skipGlobs" yaml:"skipGlobs"`
+    Extensions      map[string]string `json:"extensions" yaml:"extensions"` // ".vue" -> "eslint"
+    EslintConfig    string            `json:"eslintConfig" yaml:"eslintConfig"`
+    PrettierConfig  string            `json:"prettierConfig" yaml:"prettierConfig"`
+    SortImports     bool              `json:"sortImports" yaml:"sortImports"`
+    InternalImports []string          `json:"internalImports" yaml:"internalImports"` // "@app/", "@env/"
+
+    dir string // directory of the file; relative config paths resolve against it
+}
+
+// extensionRoutes overrides toolFor for extensions set in the config file or
+// with -map-ext (see routeExtension).
+var extensionRoutes = map[string]string{}
+
+// findConfigFile returns the first config file found in dir or its parents,
+// stopping at the directory that contains .git. It returns "" if there is none.
+func findConfigFile(dir string) string {
+    for {
+        for _, name := range configFileNames {
+            candidate := filepath.Join(dir, name)
+            if info, err := os.Stat(candidate); err == nil && !info.IsDir() {
+                return candidate
+            }
+        }
+        if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+            return ""
+        }
+        parent := filepath.Dir(dir)
+        if parent == dir {
+            return ""
+        }
+        dir = parent
+    }
+}
+
+// loadConfigFile parses a YAML or JSON config file, chosen by extension.
+func loadConfigFile(path string) (*fileConfig, error) {
+    content, err := os.ReadFile(path)
+    if err != nil {
+        return nil, err
+    }
+    var cfg fileConfig
+    if strings.EqualFold(filepath.Ext(path), ".json") {
+        err = json.Unmarshal(content, &cfg)
+    } else {
+        err = yaml.Unmarshal(content, &cfg)
+    }
+    if err != nil {
+        return nil, err
+    }
+    cfg.dir = filepath.Dir(path)
+    return &cfg, nil
+}
+
+// applyConfig copies config file values into the flag-backed settings,
+// skipping every flag that was given explicitly on the command line.
+func applyConfig(cfg *fileConfig, indentFlag *string) error {
+    set := make(map[string]bool)
+    flag.Visit(func(f *flag.Flag) { set[f.Name] = true })
+
+    if cfg.Indent != "" && !set["indent"] {
+        *indentFlag = cfg.Indent
+    }
+    if cfg.BraceStyle != "" && !set["brace-style"] {
+        braceStyle = cfg.BraceStyle
+    }
+    if cfg.PackageManager != "" && !set["package-manager"] {
+        packageManager = cfg.PackageManager
+    }
+    if len(cfg.SkipGlobs) > 0 && !set["skip-glob"] {
+        skipGlobs = cfg.SkipGlobs
+        skipGlobsDir = cfg.dir
+    }
+    if cfg.EslintConfig != "" && !set["eslint-config"] {
+        eslintConfig = cfg.resolve(cfg.EslintConfig)
+    }
+    if cfg.PrettierConfig != "" && !set["prettier-config"] {
+        prettierConfig = cfg.resolve(cfg.PrettierConfig)
+    }
+    if cfg.SortImports && !set["sort-imports"] {
+        sortImports = true
+    }
+    if len(cfg.InternalImports) > 0 {
+        internalImports = cfg.InternalImports
+    }
+    for ext, tool := range cfg.Extensions {
+        if err := routeExtension(ext, tool); err != nil {
+            return err
+        }
+    }
+    return nil
+}
+
+func (cfg *fileConfig) resolve(p string) string {
+    if filepath.IsAbs(p) {
+        return p
+    }
+    return filepath.Join(cfg.dir, p)
+}
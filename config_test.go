@@ -0,0 +1,46 @@
+package main
+
+import (
+    "os"
+    "path/filepath"
+    "strings"
+    "testing"
+)
+
+// TestConfigSkipGlobsFromParent checks that skipGlobs from a config file in
+// a parent directory of -path stay relative to that file.
+func TestConfigSkipGlobsFromParent(t *testing.T) {
+    root := t.TempDir()
+    savedRepo, savedGlobs, savedDir := repoPath, skipGlobs, skipGlobsDir
+    t.Cleanup(func() { repoPath, skipGlobs, skipGlobsDir = savedRepo, savedGlobs, savedDir })
+    repoPath = filepath.Join(root, "src")
+    if err := os.MkdirAll(repoPath, 0755); err != nil {
+        t.Fatal(err)
+    }
+    if err := os.WriteFile(filepath.Join(root, ".go-formatter.yaml"), []byte("skipGlobs:\n  - \"src/legacy/**\"\n"), 0644); err != nil {
+        t.Fatal(err)
+    }
+
+    path := findConfigFile(repoPath)
+    if path == "" {
+        t.Fatal("config file in the parent directory not found")
+    }
+    cfg, err := loadConfigFile(path)
+    if err != nil {
+        t.Fatal(err)
+    }
+    indent := ""
+    if err := applyConfig(cfg, &indent); err != nil {
+        t.Fatal(err)
+    }
+
+    skip := parseIgnore(strings.Join(skipGlobs, "\n"))
+    for file, want := range map[string]bool{
+        filepath.Join(repoPath, "legacy", "a.component.html"): true,
+        filepath.Join(repoPath, "app", "a.component.html"):    false,
+    } {
+        if got := skip.Match(skipGlobPath(file)); got != want {
+            t.Errorf("%s skipped = %t, want %t", skipGlobPath(file), got, want)
+        }
+    }
+}
@@ -0,0 +1,296 @@
+package main
+
+import (
+    "errors"
+    "fmt"
+    "os"
+    "os/exec"
+    "path/filepath"
+    "slices"
+    "strings"
+)
+
+// --- UNIFIED DIFF ---
+
+// Used by --dry-run to show what the custom formatter would change without
+// writing anything. Implements the Myers O(ND) algorithm over lines.
+
+const diffContext = 3
+
+type diffOp struct {
+    kind byte // ' ', '-' or '+'
+    text string
+}
+
+// unifiedDiff returns a unified diff between a and b, or "" if they are equal.
+func unifiedDiff(name, a, b string) string {
+    if a == b {
+        return ""
+    }
+    ops := diffLines(splitDiffLines(a), splitDiffLines(b))
+
+    var sb strings.Builder
+    fmt.Fprintf(&sb, "--- a/%s\n+++ b/%s\n", name, name)
+
+    // Walk the edit script and emit hunks with diffContext lines around changes
+    i := 0
+    for i < len(ops) {
+        if ops[i].kind == ' ' {
+            i++
+            continue
+        }
+        start := i - diffContext
+        if start < 0 {
+            start = 0
+        }
+        end := i
+        for end < len(ops) {
+            if ops[end].kind != ' ' {
+                end++
+                continue
+            }
+            // Merge changes separated by less than two context windows
+            next := end
+            for next < len(ops) && ops[next].kind == ' ' {
+                next++
+            }
+            if next < len(ops) && next-end <= 2*diffContext {
+                end = next
+                continue
+            }
+            end += diffContext
+            if end > len(ops) {
+                end = len(ops)
+            }
+            break
+        }
+
+        aStart, bStart := 1, 1
+        for _, op := range ops[:start] {
+            if op.kind != '+' {
+                aStart++
+            }
+            if op.kind != '-' {
+                bStart++
+            }
+        }
+        aLen, bLen := 0, 0
+        for _, op := range ops[start:end] {
+            if op.kind != '+' {
+                aLen++
+            }
+            if op.kind != '-' {
+                bLen++
+            }
+        }
+
+        fmt.Fprintf(&sb, "@@ -%d,%d +%d,%d @@\n", aStart, aLen, bStart, bLen)
+        for _, op := range ops[start:end] {
+            sb.WriteByte(op.kind)
+            text, noEOL := strings.CutSuffix(op.text, noNewlineMark)
+            sb.WriteString(text)
+            sb.WriteByte('\n')
+            if noEOL {
+                sb.WriteString("\\ No newline at end of file\n")
+            }
+        }
+        i = end
+    }
+    return sb.String()
+}
+
+// noNewlineMark is appended to a last line that has no newline, so it
+// differs from the same text with one and the diff shows a change that is
+// only a final newline, with git's "\ No newline at end of file" marker.
+const noNewlineMark = "\x00no newline"
+
+func splitDiffLines(s string) []string {
+    if s == "" {
+        return nil
+    }
+    lines := strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+    if !strings.HasSuffix(s, "\n") {
+        lines[len(lines)-1] += noNewlineMark
+    }
+    return lines
+}
+
+// diffLines computes a shortest edit script turning a into b. It uses the
+// linear-space variant of Myers' algorithm: find the middle snake of the
+// script, then diff the halves on either side of it, so a fully reindented
+// template of thousands of lines costs O(N+M) memory rather than O(D*(N+M)).
+func diffLines(a, b []string) []diffOp {
+    var ops []diffOp
+    diffRange(a, b, &ops)
+
+    // Within each run of changes, list the removed lines before the added
+    // ones, as diff and git do
+    for i := 0; i < len(ops); {
+        if ops[i].kind == ' ' {
+            i++
+            continue
+        }
+        j := i
+        for j < len(ops) && ops[j].kind != ' ' {
+            j++
+        }
+        slices.SortStableFunc(ops[i:j], func(x, y diffOp) int { return int(y.kind) - int(x.kind) })
+        i = j
+    }
+    return ops
+}
+
+// diffRange appends the edit script for a and b to ops.
+func diffRange(a, b []string, ops *[]diffOp) {
+    for len(a) > 0 && len(b) > 0 && a[0] == b[0] {
+        *ops = append(*ops, diffOp{' ', a[0]})
+        a, b = a[1:], b[1:]
+    }
+    common := 0
+    for common < len(a) && common < len(b) && a[len(a)-1-common] == b[len(b)-1-common] {
+        common++
+    }
+    suffix := a[len(a)-common:]
+    a, b = a[:len(a)-common], b[:len(b)-common]
+
+    if x, y, ok := middleSnake(a, b); ok {
+        diffRange(a[:x], b[:y], ops)
+        diffRange(a[x:], b[y:], ops)
+    } else {
+        // One side is empty, or they have nothing in common
+        for _, line := range a {
+            *ops = append(*ops, diffOp{'-', line})
+        }
+        for _, line := range b {
+            *ops = append(*ops, diffOp{'+', line})
+        }
+    }
+    for _, line := range suffix {
+        *ops = append(*ops, diffOp{' ', line})
+    }
+}
+
+// middleSnake runs the Myers search from both ends of a and b at once until
+// the paths overlap, and returns where to split them: a[:x], b[:y] and
+// a[x:], b[y:] are diffed on their own. a and b must not share a first or
+// last line. ok is false when no split helps.
+func middleSnake(a, b []string) (x, y int, ok bool) {
+    n, m := len(a), len(b)
+    if n == 0 || m == 0 {
+        return 0, 0, false
+    }
+    maxD := (n + m + 1) / 2
+    offset := maxD
+    forward := make([]int, 2*maxD+2)
+    backward := make([]int, 2*maxD+2)
+    for i := range forward {
+        forward[i], backward[i] = -1, -1
+    }
+    forward[offset+1], backward[offset+1] = 0, 0
+    delta := n - m
+    // With an odd delta the forward paths reach the overlap first
+    odd := delta%2 != 0
+    // Diagonals that ran off the edit graph are not extended again
+    fStart, fEnd, bStart, bEnd := 0, 0, 0, 0
+
+    split := func(x, y int) (int, int, bool) {
+        if (x == 0 && y == 0) || (x == n && y == m) {
+            return 0, 0, false
+        }
+        return x, y, true
+    }
+    for d := 0; d < maxD; d++ {
+        for k := -d + fStart; k <= d-fEnd; k += 2 {
+            i := offset + k
+            var x1 int
+            if k == -d || (k != d && forward[i-1] < forward[i+1]) {
+                x1 = forward[i+1]
+            } else {
+                x1 = forward[i-1] + 1
+            }
+            y1 := x1 - k
+            for x1 < n && y1 < m && a[x1] == b[y1] {
+                x1++
+                y1++
+            }
+            forward[i] = x1
+            switch {
+            case x1 > n:
+                fEnd += 2
+            case y1 > m:
+                fStart += 2
+            case odd:
+                if j := offset + delta - k; j >= 0 && j < len(backward) && backward[j] != -1 && x1 >= n-backward[j] {
+                    return split(x1, y1)
+                }
+            }
+        }
+        for k := -d + bStart; k <= d-bEnd; k += 2 {
+            i := offset + k
+            var x2 int
+            if k == -d || (k != d && backward[i-1] < backward[i+1]) {
+                x2 = backward[i+1]
+            } else {
+                x2 = backward[i-1] + 1
+            }
+            y2 := x2 - k
+            for x2 < n && y2 < m && a[n-x2-1] == b[m-y2-1] {
+                x2++
+                y2++
+            }
+            backward[i] = x2
+            switch {
+            case x2 > n:
+                bEnd += 2
+            case y2 > m:
+                bStart += 2
+            case !odd:
+                if j := offset + delta - k; j >= 0 && j < len(forward) && forward[j] != -1 {
+                    x1 := forward[j]
+                    if x1 >= n-x2 {
+                        return split(x1, x1-(j-offset))
+                    }
+                }
+            }
+        }
+    }
+    return 0, 0, false
+}
+
+// --- EXTERNAL DIFF TOOL ---
+
+// useDifftool shows dry-run changes in the configured `git difftool` instead
+// of printing a unified diff (-difftool).
+var useDifftool bool
+
+// openDifftool writes the proposed content to a temporary copy of file and
+// opens `git difftool --no-index` on the pair. The copy keeps the file's
+// name so the tool picks the right syntax highlighting. It blocks until the
+// tool is closed, so there's no timeout.
+func openDifftool(file string, proposed []byte) error {
+    dir, err := os.MkdirTemp("", "go-formatter-difftool-")
+    if err != nil {
+        return err
+    }
+    defer os.RemoveAll(dir)
+    formatted := filepath.Join(dir, filepath.Base(file))
+    // Not writeFile: previews are what -read-only runs are for, and the copy
+    // lives in a temp directory that is removed on return
+    if err := os.WriteFile(formatted, proposed, 0644); err != nil {
+        return err
+    }
+
+    cmd := newTimedCmd(0, "git", "difftool", "--no-prompt", "--no-index", file, formatted)
+    cmd.Dir = repoPath
+    cmd.Stdin = os.Stdin
+    cmd.Stdout = os.Stdout
+    cmd.Stderr = os.Stderr
+    logCommand(cmd)
+    err = cmd.Run()
+    // --no-index exits 1 when the files differ, which is the point here
+    var exitErr *exec.ExitError
+    if errors.As(err, &exitErr) && exitErr.ExitCode() == 1 {
+        return nil
+    }
+    return err
+}
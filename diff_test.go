@@ -0,0 +1,130 @@
+package main
+
+import (
+    "math/rand"
+    "runtime"
+    "slices"
+    "strings"
+    "testing"
+)
+
+func TestUnifiedDiffFinalNewline(t *testing.T) {
+    tests := []struct {
+        name, a, b, want string
+    }{
+        {
+            "newline added",
+            "<p>a</p>\n<p>b</p>",
+            "<p>a</p>\n<p>b</p>\n",
+            "--- a/x.html\n+++ b/x.html\n@@ -1,2 +1,2 @@\n <p>a</p>\n-<p>b</p>\n\\ No newline at end of file\n+<p>b</p>\n",
+        },
+        {
+            "newline removed",
+            "<p>a</p>\n",
+            "<p>a</p>",
+            "--- a/x.html\n+++ b/x.html\n@@ -1,1 +1,1 @@\n-<p>a</p>\n+<p>a</p>\n\\ No newline at end of file\n",
+        },
+        {
+            "last line changed without a newline on either side",
+            "<p>a</p>",
+            "<p>b</p>",
+            "--- a/x.html\n+++ b/x.html\n@@ -1,1 +1,1 @@\n-<p>a</p>\n\\ No newline at end of file\n+<p>b</p>\n\\ No newline at end of file\n",
+        },
+        {
+            "unchanged last line without a newline",
+            "<p>a</p>\n<p>z</p>",
+            "<p>b</p>\n<p>z</p>",
+            "--- a/x.html\n+++ b/x.html\n@@ -1,2 +1,2 @@\n-<p>a</p>\n+<p>b</p>\n <p>z</p>\n\\ No newline at end of file\n",
+        },
+        {"equal", "<p>a</p>", "<p>a</p>", ""},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            if got := unifiedDiff("x.html", tt.a, tt.b); got != tt.want {
+                t.Errorf("got:\n%s\nwant:\n%s", got, tt.want)
+            }
+        })
+    }
+}
+
+// checkEditScript fails unless ops turns a into b with exactly changes
+// removed and added lines.
+func checkEditScript(t *testing.T, a, b []string, ops []diffOp, changes int) {
+    t.Helper()
+    var gotA, gotB []string
+    edits := 0
+    for _, op := range ops {
+        if op.kind != '+' {
+            gotA = append(gotA, op.text)
+        }
+        if op.kind != '-' {
+            gotB = append(gotB, op.text)
+        }
+        if op.kind != ' ' {
+            edits++
+        }
+    }
+    if !slices.Equal(gotA, a) || !slices.Equal(gotB, b) {
+        t.Fatalf("edit script does not turn %q into %q: %v", a, b, ops)
+    }
+    if edits != changes {
+        t.Fatalf("%d edits for %q -> %q, want %d", edits, a, b, changes)
+    }
+}
+
+// TestDiffLinesShortest compares the edit scripts of random inputs with
+// the edit distance from the longest common subsequence.
+func TestDiffLinesShortest(t *testing.T) {
+    r := rand.New(rand.NewSource(1))
+    random := func() []string {
+        lines := make([]string, r.Intn(12))
+        for i := range lines {
+            lines[i] = string(rune('a' + r.Intn(4)))
+        }
+        return lines
+    }
+    for range 2000 {
+        a, b := random(), random()
+        lcs := make([][]int, len(a)+1)
+        for i := range lcs {
+            lcs[i] = make([]int, len(b)+1)
+        }
+        for i := len(a) - 1; i >= 0; i-- {
+            for j := len(b) - 1; j >= 0; j-- {
+                if a[i] == b[j] {
+                    lcs[i][j] = lcs[i+1][j+1] + 1
+                } else {
+                    lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+                }
+            }
+        }
+        checkEditScript(t, a, b, diffLines(a, b), len(a)+len(b)-2*lcs[0][0])
+    }
+}
+
+// TestDiffLinesLargeInput diffs a fully reindented template of a few
+// thousand lines, the worst case for -dry-run, within bounded memory.
+func TestDiffLinesLargeInput(t *testing.T) {
+    var a, b []string
+    for i := range 4000 {
+        line := "<p>line " + strings.Repeat("x", i%7) + "</p>"
+        a = append(a, "    "+line)
+        if i%500 == 0 {
+            // A few lines in common keep the search from giving up early
+            b = append(b, "    "+line)
+        } else {
+            b = append(b, "        "+line)
+        }
+    }
+
+    var before, after runtime.MemStats
+    runtime.GC()
+    runtime.ReadMemStats(&before)
+    ops := diffLines(a, b)
+    runtime.ReadMemStats(&after)
+
+    checkEditScript(t, a, b, ops, 2*(4000-8))
+    if allocated := after.TotalAlloc - before.TotalAlloc; allocated > 64<<20 {
+        t.Errorf("diffLines allocated %d MB", allocated>>20)
+    }
+}
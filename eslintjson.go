@@ -0,0 +1,105 @@
+package main
+
+import (
+    "encoding/json"
+    "fmt"
+    "io"
+    "path/filepath"
+    "strings"
+)
+
+// --- ESLINT JSON RESULTS ---
+
+// lintCounts is what ESLint still reports for one file.
+type lintCounts struct {
+    errors, warnings int
+    messages         []lintMessage
+}
+
+// lintMessage is one problem ESLint reports, as used by the structured reports.
+type lintMessage struct {
+    ruleID             string // "" for parse errors
+    severity           int    // 1 warning, 2 error
+    message            string
+    line, column       int
+    endLine, endColumn int
+}
+
+// eslintFileReport is one file's entry in the output of `eslint --format json`.
+type eslintFileReport struct {
+    path   string
+    counts lintCounts
+    output *string // the fixed source, only with --fix-dry-run and only if a fix applied
+}
+
+// parseEslintJSON parses the output of ESLint's JSON formatter.
+func parseEslintJSON(out []byte) ([]eslintFileReport, error) {
+    var raw []struct {
+        FilePath     string  `json:"filePath"`
+        ErrorCount   int     `json:"errorCount"`
+        WarningCount int     `json:"warningCount"`
+        Output       *string `json:"output"`
+        Messages     []struct {
+            RuleID    string `json:"ruleId"`
+            Severity  int    `json:"severity"`
+            Message   string `json:"message"`
+            Line      int    `json:"line"`
+            Column    int    `json:"column"`
+            EndLine   int    `json:"endLine"`
+            EndColumn int    `json:"endColumn"`
+        } `json:"messages"`
+    }
+    if err := json.Unmarshal(out, &raw); err != nil {
+        return nil, err
+    }
+
+    reports := make([]eslintFileReport, 0, len(raw))
+    for _, r := range raw {
+        report := eslintFileReport{
+            path:   filepath.Clean(r.FilePath),
+            counts: lintCounts{errors: r.ErrorCount, warnings: r.WarningCount},
+            output: r.Output,
+        }
+        for _, m := range r.Messages {
+            report.counts.messages = append(report.counts.messages, lintMessage{
+                ruleID: m.RuleID, severity: m.Severity, message: m.Message,
+                line: m.Line, column: m.Column, endLine: m.EndLine, endColumn: m.EndColumn,
+            })
+        }
+        reports = append(reports, report)
+    }
+    return reports, nil
+}
+
+// countsByPath indexes parsed reports by file path.
+func countsByPath(reports []eslintFileReport) map[string]lintCounts {
+    counts := make(map[string]lintCounts, len(reports))
+    for _, r := range reports {
+        counts[r.path] = r.counts
+    }
+    return counts
+}
+
+// printLintMessages prints parsed messages in the shape of ESLint's default
+// ("stylish") output, so the log reads the same when its JSON was captured.
+func printLintMessages(w io.Writer, reports []eslintFileReport) {
+    for _, r := range reports {
+        if len(r.counts.messages) == 0 {
+            continue
+        }
+        fmt.Fprintf(w, "\n%s\n", relPath(r.path))
+        for _, m := range r.counts.messages {
+            line := fmt.Sprintf("  %d:%d  %-7s  %s  %s", m.line, m.column, severityName(m.severity), m.message, m.ruleID)
+            fmt.Fprintln(w, strings.TrimRight(line, " "))
+        }
+    }
+}
+
+// severityName turns ESLint's numeric severity into "error" or "warning",
+// which are also SARIF's level names.
+func severityName(severity int) string {
+    if severity >= 2 {
+        return "error"
+    }
+    return "warning"
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+    "errors"
+    "fmt"
+    "go/format"
+    "io/fs"
+    "os"
+    "time"
+
+    "formatter/angular"
+)
+
+// --- IN-PROCESS FORMATTERS ---
+
+// Formatter is a pure Go formatter for the content of a single file.
+// Register one with registerFormatter to handle a new extension without
+// shelling out to Node.
+type Formatter interface {
+    Format(src []byte) ([]byte, error)
+}
+
+type namedFormatter struct {
+    name string
+    Formatter
+}
+
+// formatters maps a lowercase extension (".html") to its in-process formatter.
+var formatters = map[string]namedFormatter{}
+
+// registerFormatter registers f under a display name (used in reports) for exts.
+func registerFormatter(name string, f Formatter, exts ...string) {
+    for _, ext := range exts {
+        formatters[ext] = namedFormatter{name: name, Formatter: f}
+    }
+}
+
+func init() {
+    registerFormatter("angular", angularFormatter{}, ".html")
+    registerFormatter("gofmt", gofmtFormatter{}, ".go")
+}
+
+// angularFormatter applies the Allman brace expansion for Angular control flow.
+// It runs after Prettier as part of runHtmlProcessing.
+type angularFormatter struct{}
+
+func (angularFormatter) Format(src []byte) ([]byte, error) {
+    out, err := angular.FormatAngularTemplate(string(src), angularOptions())
+    return []byte(out), err
+}
+
+// angularOptions collects the -indent, -brace-style, -max-blank-lines and
+// -final-newline settings for the angular package.
+func angularOptions() angular.Options {
+    return angular.Options{
+        Indent:             indentUnit,
+        BraceStyle:         braceStyle,
+        MaxBlankLines:      maxBlankLines,
+        NoBlankLines:       maxBlankLines == 0,
+        KeepMissingNewline: !finalNewline,
+    }
+}
+
+// gofmtFormatter formats Go source the same way gofmt does.
+type gofmtFormatter struct{}
+
+func (gofmtFormatter) Format(src []byte) ([]byte, error) {
+    return format.Source(src)
+}
+
+// runNativeFormatters runs files whose extension only has an in-process formatter.
+func runNativeFormatters(files []string) {
+    logf("Running built-in formatters on %d file(s)...\n", len(files))
+    for _, file := range files {
+        applyFormatter(file, formatters[extOf(file)])
+    }
+    logln(green("Built-in formatting finished."))
+}
+
+// applyFormatter formats one file in place, or prints a diff in dry-run mode.
+func applyFormatter(file string, f namedFormatter) {
+    applyTransform(file, f.name, f.Format)
+}
+
+// applyTransform rewrites one file with transform and records the result
+// under name. In dry-run mode it prints a diff instead of writing.
+func applyTransform(file, name string, transform func(src []byte) ([]byte, error)) {
+    result := fileResult{path: file, tool: name}
+    start := time.Now()
+    defer func() {
+        result.elapsed = time.Since(start)
+        recordResult(result)
+    }()
+
+    info, err := os.Stat(file)
+    if errors.Is(err, fs.ErrNotExist) {
+        logf("Skipped %s (deleted during run).\n", relPath(file))
+        result.skipped = true
+        return
+    }
+    if err != nil {
+        warnf("Error reading %s: %v\n", file, err)
+        result.err = err
+        return
+    }
+    content, err := os.ReadFile(file)
+    if errors.Is(err, fs.ErrNotExist) {
+        logf("Skipped %s (deleted during run).\n", relPath(file))
+        result.skipped = true
+        return
+    }
+    if err != nil {
+        warnf("Error reading %s: %v\n", file, err)
+        result.err = err
+        return
+    }
+
+    newContent, err := transform(content)
+    var unbalanced *angular.UnbalancedBraceError
+    if errors.As(err, &unbalanced) {
+        warnf("%s\n", yellow(fmt.Sprintf("Warning: %s:%d: unbalanced '}'; file left unchanged.", relPath(file), unbalanced.Line)))
+        // A template error, not a pending change: the exit code stays for
+        // "needs formatting" (1) and tool failures (2)
+        result.err = err
+        return
+    }
+    if err != nil {
+        warnf("Error formatting %s: %v\n", file, err)
+        result.err = err
+        setExitStatus(2)
+        return
+    }
+
+    if string(newContent) == string(content) {
+        return
+    }
+    result.changed = true
+    if dryRun {
+        warnf("Would reformat: %s\n", file)
+        setExitStatus(1)
+        if !useDifftool {
+            warnf("%s", unifiedDiff(relPath(file), string(content), string(newContent)))
+        } else if err := openDifftool(file, newContent); err != nil {
+            warnf("Could not open git difftool for %s: %v\n", relPath(file), err)
+        }
+        return
+    }
+    // Keep the original mode bits (e.g. 0600) rather than a hardcoded default
+    if err := writeFile(file, newContent, info.Mode().Perm()); err != nil {
+        warnf("Error writing %s: %v\n", file, err)
+        result.err = err
+    }
+}
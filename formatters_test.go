@@ -0,0 +1,105 @@
+package main
+
+import (
+    "io"
+    "os"
+    "path/filepath"
+    "strings"
+    "testing"
+)
+
+func TestApplyFormatterKeepsModeAndLineEndings(t *testing.T) {
+    dir := t.TempDir()
+    savedRepo := repoPath
+    repoPath = dir
+    t.Cleanup(func() { repoPath = savedRepo; resetResults() })
+
+    file := filepath.Join(dir, "a.component.html")
+    in := "<div>\r\n    @if (a) {\r\n        <p>a</p>\r\n    }\r\n</div>\r\n"
+    if err := os.WriteFile(file, []byte(in), 0600); err != nil {
+        t.Fatal(err)
+    }
+    applyFormatter(file, formatters[".html"])
+
+    info, err := os.Stat(file)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if mode := info.Mode().Perm(); mode != 0600 {
+        t.Errorf("mode after rewrite = %o, want 600", mode)
+    }
+    out, _ := os.ReadFile(file)
+    if string(out) == in {
+        t.Fatal("file was not rewritten")
+    }
+    if lines := strings.Count(string(out), "\n"); lines != strings.Count(string(out), "\r\n") {
+        t.Errorf("rewrite mixed line endings:\n%q", out)
+    }
+}
+
+func TestDeletedDuringRun(t *testing.T) {
+    dir := t.TempDir()
+    savedRepo, savedOut := repoPath, logOut
+    repoPath, logOut = dir, io.Discard
+    t.Cleanup(func() { repoPath, logOut = savedRepo, savedOut })
+
+    tests := []struct {
+        name string
+        run  func(file string)
+    }{
+        {"applyTransform", func(file string) {
+            applyTransform(file, "angular", func(src []byte) ([]byte, error) {
+                t.Error("transform ran on a deleted file")
+                return src, nil
+            })
+        }},
+        {"dropDeleted", func(file string) {
+            if kept := dropDeleted([]string{file}, "prettier", io.Discard); len(kept) != 0 {
+                t.Errorf("dropDeleted kept %v", kept)
+            }
+        }},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            resetResults()
+            t.Cleanup(resetResults)
+            file := filepath.Join(dir, "gone.component.html")
+            if err := os.WriteFile(file, []byte("<p>a</p>\n"), 0644); err != nil {
+                t.Fatal(err)
+            }
+            os.Remove(file)
+
+            tt.run(file)
+            if len(results) != 1 {
+                t.Fatalf("got %d results, want 1", len(results))
+            }
+            if r := results[0]; !r.skipped || r.err != nil || r.failed() {
+                t.Errorf("result = %+v, want skipped without an error", r)
+            }
+        })
+    }
+}
+
+func TestUnbalancedTemplateKeepsExitStatus(t *testing.T) {
+    dir := t.TempDir()
+    savedRepo, savedOut, savedStatus := repoPath, logOut, exitStatus
+    repoPath, logOut, exitStatus = dir, io.Discard, 0
+    t.Cleanup(func() { repoPath, logOut, exitStatus = savedRepo, savedOut, savedStatus; resetResults() })
+
+    file := filepath.Join(dir, "a.component.html")
+    in := "<div>\n    @if (a) {\n        <p>a</p>\n    }\n    }\n</div>\n"
+    if err := os.WriteFile(file, []byte(in), 0644); err != nil {
+        t.Fatal(err)
+    }
+    applyFormatter(file, htmlFormatter())
+
+    if out, _ := os.ReadFile(file); string(out) != in {
+        t.Errorf("file was rewritten:\n%s", out)
+    }
+    if len(results) != 1 || results[0].err == nil {
+        t.Errorf("results = %+v, want one failed result", results)
+    }
+    if exitStatus != 0 {
+        t.Errorf("exit status = %d, want 0: 1 means the file needs formatting", exitStatus)
+    }
+}
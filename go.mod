@@ -1,3 +1,11 @@
 module formatter
 
 go 1.25.6
+
+require (
+	github.com/fsnotify/fsnotify v1.9.0
+	golang.org/x/net v0.50.0
+	gopkg.in/yaml.v3 v3.0.1
+)
+
+require golang.org/x/sys v0.41.0 // indirect
@@ -0,0 +1,200 @@
+package main
+
+import (
+    "os"
+    "path"
+    "path/filepath"
+    "strings"
+    "sync"
+    "time"
+)
+
+// --- IGNORE FILES ---
+
+// ignoreFileName is the tool-specific ignore file read from the repo root.
+// It uses gitignore syntax and is independent of .gitignore/.eslintignore/.prettierignore.
+const ignoreFileName = ".go-formatter-ignore"
+
+// angularIgnoreFileName lists templates that skip only the custom Allman pass,
+// in the same syntax; they are still formatted by Prettier.
+const angularIgnoreFileName = ".angularformatignore"
+
+// generatedPrefixes are repo-relative directories holding build output or
+// vendored code. Files under them are skipped unless -include-generated is set.
+var generatedPrefixes = []string{"node_modules/", "dist/", ".angular/"}
+
+// includeGenerated disables the generatedPrefixes exclusion.
+var includeGenerated bool
+
+// isGenerated reports whether the repo-relative, slash-separated path lives
+// under one of generatedPrefixes. Only whole leading directories match, so
+// "src/dist-view/a.html" or "distribution/a.html" are not affected.
+func isGenerated(rel string) bool {
+    if includeGenerated {
+        return false
+    }
+    rel = strings.TrimPrefix(rel, "./")
+    for _, prefix := range generatedPrefixes {
+        if strings.HasPrefix(rel, prefix) {
+            return true
+        }
+    }
+    return false
+}
+
+type ignoreRule struct {
+    pattern  string // slash-separated, without the leading "/" or trailing "/"
+    negate   bool   // "!pattern" re-includes a previously ignored path
+    dirOnly  bool   // "pattern/" only matches directories
+    anchored bool   // a slash in the pattern anchors it to the repo root
+}
+
+// ignoreMatcher evaluates gitignore-style rules against repo-relative paths.
+// A nil matcher ignores nothing.
+type ignoreMatcher struct {
+    rules []ignoreRule
+}
+
+// loadIgnoreFile parses the ignore file at path. A missing file yields nil.
+func loadIgnoreFile(path string) *ignoreMatcher {
+    content, err := os.ReadFile(path)
+    if err != nil {
+        return nil
+    }
+    return parseIgnore(string(content))
+}
+
+// optOutCache is .angularformatignore as last parsed, with what identified
+// the file then, so -serve and -watch still see edits to it.
+var optOutCache struct {
+    sync.Mutex
+    path    string
+    modTime time.Time
+    size    int64
+    matcher *ignoreMatcher
+}
+
+// angularOptOut returns the parsed .angularformatignore of repoPath. It is
+// read once and only parsed again when the file changes, so the Allman pass
+// can ask for every template of a run.
+func angularOptOut() *ignoreMatcher {
+    file := filepath.Join(repoPath, angularIgnoreFileName)
+    var modTime time.Time
+    size := int64(-1)
+    if info, err := os.Stat(file); err == nil {
+        modTime, size = info.ModTime(), info.Size()
+    }
+    optOutCache.Lock()
+    defer optOutCache.Unlock()
+    if optOutCache.path != file || !optOutCache.modTime.Equal(modTime) || optOutCache.size != size {
+        optOutCache.path, optOutCache.modTime, optOutCache.size = file, modTime, size
+        optOutCache.matcher = loadIgnoreFile(file)
+    }
+    return optOutCache.matcher
+}
+
+func parseIgnore(content string) *ignoreMatcher {
+    m := &ignoreMatcher{}
+    for _, line := range strings.Split(content, "\n") {
+        line = strings.TrimRight(line, " \t\r")
+        if line == "" || strings.HasPrefix(line, "#") {
+            continue
+        }
+
+        var rule ignoreRule
+        if strings.HasPrefix(line, "!") {
+            rule.negate = true
+            line = line[1:]
+        } else if strings.HasPrefix(line, `\!`) || strings.HasPrefix(line, `\#`) {
+            line = line[1:]
+        }
+        // Accept Windows-style patterns (src\legacy\**); paths are matched
+        // with forward slashes on every platform. A no-op elsewhere, where
+        // a backslash is a valid file name character.
+        line = filepath.ToSlash(line)
+        if strings.HasSuffix(line, "/") {
+            rule.dirOnly = true
+            line = strings.TrimRight(line, "/")
+        }
+        if strings.Contains(line, "/") {
+            rule.anchored = true
+            line = strings.TrimPrefix(line, "/")
+        }
+        if line == "" {
+            continue
+        }
+        rule.pattern = line
+        m.rules = append(m.rules, rule)
+    }
+    return m
+}
+
+// Match reports whether the repo-relative, slash-separated file path is ignored.
+// Rules are evaluated in order and the last matching rule wins, so later
+// negations can re-include files excluded by earlier patterns.
+func (m *ignoreMatcher) Match(rel string) bool {
+    if m == nil {
+        return false
+    }
+    rel = strings.TrimPrefix(rel, "./")
+
+    // A file matches a rule directly, or through any of its parent directories
+    segments := strings.Split(rel, "/")
+    ignored := false
+    for _, rule := range m.rules {
+        matched := false
+        for i := 1; i <= len(segments) && !matched; i++ {
+            isDir := i < len(segments)
+            if rule.dirOnly && !isDir {
+                continue
+            }
+            matched = rule.matches(strings.Join(segments[:i], "/"))
+        }
+        if matched {
+            ignored = !rule.negate
+        }
+    }
+    return ignored
+}
+
+func (r ignoreRule) matches(p string) bool {
+    if r.anchored {
+        return globMatch(r.pattern, p)
+    }
+    return globMatch(r.pattern, path.Base(p))
+}
+
+// globMatch matches a slash-separated path against a glob pattern where "**"
+// spans any number of directories and other segments use path.Match syntax.
+func globMatch(pattern, p string) bool {
+    return matchSegments(strings.Split(pattern, "/"), strings.Split(p, "/"))
+}
+
+func matchSegments(pattern, parts []string) bool {
+    for len(pattern) > 0 {
+        if pattern[0] == "**" {
+            // Collapse consecutive ** and try every possible split point
+            for len(pattern) > 0 && pattern[0] == "**" {
+                pattern = pattern[1:]
+            }
+            if len(pattern) == 0 {
+                return true
+            }
+            for i := 0; i <= len(parts); i++ {
+                if matchSegments(pattern, parts[i:]) {
+                    return true
+                }
+            }
+            return false
+        }
+        if len(parts) == 0 {
+            return false
+        }
+        if ok, err := path.Match(pattern[0], parts[0]); err != nil || !ok {
+            return false
+        }
+        pattern = pattern[1:]
+        parts = parts[1:]
+    }
+    return len(parts) == 0
+}
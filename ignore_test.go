@@ -0,0 +1,85 @@
+package main
+
+import (
+    "os"
+    "path/filepath"
+    "runtime"
+    "testing"
+)
+
+// TestPathSeparators checks that paths and patterns written with either
+// separator end up compared in the same slash form. Backslashes are only
+// separators on Windows; elsewhere they are part of a file name.
+func TestPathSeparators(t *testing.T) {
+    savedRepo := repoPath
+    repoPath = filepath.Join(t.TempDir(), "repo")
+    t.Cleanup(func() { repoPath = savedRepo })
+    onWindows := runtime.GOOS == "windows"
+    native := filepath.Join(repoPath, "src", "legacy", "a.component.html")
+
+    if got := relPath(native); got != "src/legacy/a.component.html" {
+        t.Errorf("relPath(%q) = %q, want slashes", native, got)
+    }
+
+    patterns := []struct {
+        pattern string
+        want    bool
+    }{
+        {"src/legacy/**", true},
+        {"src/**/*.html", true},
+        {`src\legacy\**`, onWindows},
+        {`src\**\*.html`, onWindows},
+        {"src/other/**", false},
+    }
+    for _, tt := range patterns {
+        if got := parseIgnore(tt.pattern).Match(relPath(native)); got != tt.want {
+            t.Errorf("pattern %q matching %q = %t, want %t", tt.pattern, relPath(native), got, tt.want)
+        }
+    }
+
+    outputs := []struct {
+        output string
+        want   bool
+    }{
+        {"[warn] src/legacy/a.component.html", true},
+        {"[warn] " + filepath.ToSlash(native), true},
+        {`[warn] src\legacy\a.component.html`, onWindows},
+        {"[warn] " + native, true},
+        {"[error] src/legacy/a.component.html", false},
+        {"[warn] src/legacy/b.component.html", false},
+    }
+    for _, tt := range outputs {
+        if got := prettierNamed(tt.output, "warn", native); got != tt.want {
+            t.Errorf("prettierNamed(%q) = %t, want %t", tt.output, got, tt.want)
+        }
+    }
+}
+
+func TestAngularOptOutParsedOnce(t *testing.T) {
+    savedRepo := repoPath
+    repoPath = t.TempDir()
+    t.Cleanup(func() { repoPath = savedRepo })
+    file := filepath.Join(repoPath, angularIgnoreFileName)
+
+    if m := angularOptOut(); m.Match("src/a.html") {
+        t.Fatal("matched without an ignore file")
+    }
+    if err := os.WriteFile(file, []byte("src/legacy/**\n"), 0644); err != nil {
+        t.Fatal(err)
+    }
+    first := angularOptOut()
+    if !first.Match("src/legacy/a.html") {
+        t.Fatal("rule not applied")
+    }
+    if angularOptOut() != first {
+        t.Error("an unchanged file was parsed again")
+    }
+
+    // An edit (as under -serve or -watch) is picked up
+    if err := os.WriteFile(file, []byte("src/legacy/**\nsrc/old/**\n"), 0644); err != nil {
+        t.Fatal(err)
+    }
+    if !angularOptOut().Match("src/old/a.html") {
+        t.Error("edited ignore file not reloaded")
+    }
+}
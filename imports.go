@@ -0,0 +1,190 @@
+package main
+
+import (
+    "regexp"
+    "slices"
+    "strings"
+)
+
+// --- IMPORT SORTING ---
+
+// sortImports reorders the import block of TS files after ESLint (-sort-imports).
+var sortImports bool
+
+// internalImports are the module prefixes (tsconfig path aliases) that count
+// as the project's own code; set with internalImports in the config file.
+var internalImports = []string{"@/", "~/", "src/", "app/"}
+
+// importSource matches the module specifier that ends an import statement,
+// "from './x';" or the bare "'zone.js';" of a side-effect import.
+var importSource = regexp.MustCompile(`(?:^|\sfrom\s*|^import\s*)['"]([^'"]+)['"]\s*;?\s*(?://.*)?$`)
+
+// Import groups, in the order they are written.
+const (
+    importExternal = iota
+    importInternal
+    importRelative
+)
+
+// runImportSort sorts the import block of each TypeScript file in place.
+func runImportSort(files []string) {
+    if !sortImports {
+        return
+    }
+    for _, file := range files {
+        if ext := extOf(file); ext != ".ts" && ext != ".tsx" {
+            continue
+        }
+        applyTransform(file, "imports", func(src []byte) ([]byte, error) {
+            return []byte(sortImportBlock(string(src))), nil
+        })
+    }
+}
+
+// importStmt is one statement of the import block, with the // comment lines
+// directly above it, which move with it.
+type importStmt struct {
+    lines      []string
+    source     string
+    sideEffect bool // "import 'zone.js';" - never moved past
+}
+
+// sortImportBlock rewrites the imports at the top of a source file as
+// external, internal (internalImports) and relative groups, one blank line
+// between groups, each sorted by module path. Only whole statements move,
+// exactly as written; side-effect imports keep their place, splitting the
+// block into runs that are sorted on their own. Everything before the first
+// import and from the first line that is not part of the block on is left
+// alone, and so is any block the scan doesn't understand.
+func sortImportBlock(src string) string {
+    crlf := strings.Contains(src, "\r\n")
+    if crlf {
+        src = strings.ReplaceAll(src, "\r\n", "\n")
+    }
+    lines := strings.Split(src, "\n")
+
+    first := slices.IndexFunc(lines, isImportLine)
+    if first < 0 {
+        return restoreCRLF(src, crlf)
+    }
+    // // comment lines right above the first import belong to it
+    for first > 0 && strings.HasPrefix(strings.TrimSpace(lines[first-1]), "//") {
+        first--
+    }
+
+    var stmts []importStmt
+    var pending []string // comment lines waiting for their import
+    end := first         // first line after the block
+    for i := first; i < len(lines); i++ {
+        trimmed := strings.TrimSpace(lines[i])
+        switch {
+        case trimmed == "":
+            continue
+        case strings.HasPrefix(trimmed, "//"):
+            pending = append(pending, lines[i])
+            continue
+        case !isImportLine(lines[i]):
+        default:
+            stmt := importStmt{lines: append(pending, lines[i])}
+            pending = nil
+            for {
+                m := importSource.FindStringSubmatch(strings.TrimSpace(lines[i]))
+                if m != nil {
+                    stmt.source = m[1]
+                    break
+                }
+                // "import x = require('y')", or a statement that never ends
+                if strings.Contains(lines[i], "require(") || i+1 == len(lines) || isImportLine(lines[i+1]) {
+                    return restoreCRLF(src, crlf)
+                }
+                i++
+                stmt.lines = append(stmt.lines, lines[i])
+            }
+            rest := strings.TrimSpace(strings.TrimPrefix(trimmed, "import"))
+            stmt.sideEffect = strings.HasPrefix(rest, "'") || strings.HasPrefix(rest, `"`)
+            stmts = append(stmts, stmt)
+            end = i + 1
+            continue
+        }
+        break
+    }
+    if len(stmts) < 2 {
+        return restoreCRLF(src, crlf)
+    }
+
+    var chunks [][]string
+    var run []importStmt
+    flush := func() {
+        chunks = append(chunks, groupImports(run)...)
+        run = nil
+    }
+    for i, stmt := range stmts {
+        if !stmt.sideEffect {
+            run = append(run, stmt)
+            continue
+        }
+        flush()
+        if i > 0 && stmts[i-1].sideEffect {
+            chunks[len(chunks)-1] = append(chunks[len(chunks)-1], stmt.lines...)
+        } else {
+            chunks = append(chunks, slices.Clone(stmt.lines))
+        }
+    }
+    flush()
+
+    var block []string
+    for i, chunk := range chunks {
+        if i > 0 {
+            block = append(block, "")
+        }
+        block = append(block, chunk...)
+    }
+
+    out := slices.Concat(lines[:first], block, lines[end:])
+    return restoreCRLF(strings.Join(out, "\n"), crlf)
+}
+
+// groupImports sorts a run of imports into its non-empty groups. Ties keep
+// their order, so "import type" and value imports of one module stay put.
+func groupImports(stmts []importStmt) [][]string {
+    slices.SortStableFunc(stmts, func(a, b importStmt) int {
+        if ga, gb := importGroup(a.source), importGroup(b.source); ga != gb {
+            return ga - gb
+        }
+        return strings.Compare(strings.ToLower(a.source), strings.ToLower(b.source))
+    })
+    var groups [][]string
+    for i, stmt := range stmts {
+        if i == 0 || importGroup(stmt.source) != importGroup(stmts[i-1].source) {
+            groups = append(groups, nil)
+        }
+        groups[len(groups)-1] = append(groups[len(groups)-1], stmt.lines...)
+    }
+    return groups
+}
+
+func importGroup(source string) int {
+    if strings.HasPrefix(source, ".") {
+        return importRelative
+    }
+    for _, prefix := range internalImports {
+        if strings.HasPrefix(source, prefix) {
+            return importInternal
+        }
+    }
+    return importExternal
+}
+
+// isImportLine reports whether a line starts an import statement; a dynamic
+// import() call or import.meta does not.
+func isImportLine(line string) bool {
+    rest, ok := strings.CutPrefix(line, "import")
+    return ok && rest != "" && strings.ContainsAny(rest[:1], " \t{*'\"")
+}
+
+func restoreCRLF(s string, crlf bool) string {
+    if crlf {
+        return strings.ReplaceAll(s, "\n", "\r\n")
+    }
+    return s
+}
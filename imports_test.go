@@ -0,0 +1,43 @@
+package main
+
+import "testing"
+
+func TestSortImportBlock(t *testing.T) {
+    tests := []struct {
+        name     string
+        in, want string
+    }{
+        {
+            "multi-line import",
+            "import { b } from './b';\nimport { a,\n    c } from 'rxjs';\n\nexport class X {}\n",
+            "import { a,\n    c } from 'rxjs';\n\nimport { b } from './b';\n\nexport class X {}\n",
+        },
+        {
+            "side-effect imports keep their place",
+            "import { z } from 'z';\nimport { a } from 'a';\nimport 'zone.js';\nimport { c } from 'c';\nimport './init';\n",
+            "import { a } from 'a';\nimport { z } from 'z';\n\nimport 'zone.js';\n\nimport { c } from 'c';\n\nimport './init';\n",
+        },
+        {
+            "comments move with their import",
+            "// Angular\nimport { Component } from '@angular/core';\n// local\nimport { b } from './b';\n// router\nimport { Router } from '@angular/router';\n",
+            "// Angular\nimport { Component } from '@angular/core';\n// router\nimport { Router } from '@angular/router';\n\n// local\nimport { b } from './b';\n",
+        },
+        {
+            "crlf",
+            "import { b } from './b';\r\nimport { a } from 'src/a';\r\nimport { c } from 'c';\r\n",
+            "import { c } from 'c';\r\n\r\nimport { a } from 'src/a';\r\n\r\nimport { b } from './b';\r\n",
+        },
+        {
+            "no imports",
+            "const m = await import('./m');\nexport { m };\n",
+            "const m = await import('./m');\nexport { m };\n",
+        },
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            if got := sortImportBlock(tt.in); got != tt.want {
+                t.Errorf("got:\n%q\nwant:\n%q", got, tt.want)
+            }
+        })
+    }
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+    "fmt"
+    "os"
+    "path/filepath"
+    "strings"
+)
+
+// --- INIT ---
+
+// initRepo writes the configs into the repository instead of formatting (-init).
+var initRepo bool
+
+// forceInit overwrites existing files without asking (-force).
+var forceInit bool
+
+// starterConfig is the .go-formatter.yaml written by -init. It points the
+// tool at the copied configs so edits to them take effect on the next run.
+// The extension list is the routing table -print-config reports, so the two
+// never disagree.
+func starterConfig() string {
+    var handlers []string
+    for _, h := range toolHandlers {
+        if h.name != "native" {
+            handlers = append(handlers, h.name)
+        }
+    }
+    var routes strings.Builder
+    for _, r := range routingTable() {
+        // Built-in formatters are not a handler an extension can be routed to
+        if r.Handler != "native" {
+            fmt.Fprintf(&routes, "#   %s: %s\n", r.Extension, r.Handler)
+        }
+    }
+    return `# go-formatter settings. Command-line flags override everything here.
+
+# Indent per brace level for the custom HTML pass: a number of spaces or "tab"
+indent: "4"
+
+# Where control flow braces go: "allman" (own line) or "k&r" (end of the @if line)
+braceStyle: allman
+
+# Written by -init from the configs embedded in go-formatter; edit freely
+eslintConfig: eslint.config.mjs
+prettierConfig: .prettierrc
+
+# Files to leave alone, in .gitignore syntax
+skipGlobs: []
+
+# Route extensions to a handler (` + strings.Join(handlers, ", ") + `).
+# The current routing, as -print-config shows it; uncomment to change a line
+# or add one such as ".cshtml: prettier"
+# extensions:
+` + routes.String()
+}
+
+// runInit copies the embedded ESLint and Prettier configs and a starter
+// settings file into repoPath. Existing files are only replaced after
+// confirmation, or with -force; without a terminal they are kept.
+func runInit() {
+    eslintRc, _ := configFiles.ReadFile("configs/eslint.config.mjs")
+    prettierRc, _ := configFiles.ReadFile("configs/.prettierrc")
+    files := []struct {
+        name    string
+        content []byte
+    }{
+        {"eslint.config.mjs", eslintRc},
+        {".prettierrc", prettierRc},
+        {configFileNames[0], []byte(starterConfig())},
+    }
+
+    for _, f := range files {
+        dest := filepath.Join(repoPath, f.name)
+        if _, err := os.Stat(dest); err == nil && !forceInit {
+            if !stdinIsTerminal() || !confirm(relPath(dest)+" already exists. Overwrite?") {
+                warnf("Kept existing %s (use -force to overwrite).\n", relPath(dest))
+                continue
+            }
+        }
+        if err := writeFile(dest, f.content, 0644); err != nil {
+            warnf("Error writing %s: %v\n", dest, err)
+            setExitStatus(2)
+            continue
+        }
+        logf("Wrote %s\n", relPath(dest))
+    }
+
+    logln("\nThe ESLint config imports typescript-eslint and @stylistic/eslint-plugin, which ESLint")
+    logln("resolves from the repository. Add them as dev dependencies if the project lacks them:")
+    logln("  npm install -D eslint typescript-eslint @stylistic/eslint-plugin")
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+    "strings"
+    "testing"
+
+    "gopkg.in/yaml.v3"
+)
+
+// TestStarterConfigMatchesRouting checks that -init writes every route and
+// handler that -print-config knows about, as valid YAML.
+func TestStarterConfigMatchesRouting(t *testing.T) {
+    config := starterConfig()
+    var cfg fileConfig
+    if err := yaml.Unmarshal([]byte(config), &cfg); err != nil {
+        t.Fatalf("starter config is not valid YAML: %v", err)
+    }
+    for _, r := range routingTable() {
+        if r.Handler == "native" {
+            continue
+        }
+        if !strings.Contains(config, "#   "+r.Extension+": "+r.Handler+"\n") {
+            t.Errorf("starter config lacks the route %s: %s", r.Extension, r.Handler)
+        }
+    }
+    for _, h := range toolHandlers {
+        if h.name != "native" && !strings.Contains(config, h.name+",") && !strings.Contains(config, h.name+")") {
+            t.Errorf("starter config lacks the handler %s", h.name)
+        }
+    }
+}
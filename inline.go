@@ -0,0 +1,275 @@
+package main
+
+import (
+    "errors"
+    "fmt"
+    "regexp"
+    "strings"
+
+    "formatter/angular"
+)
+
+// --- INLINE TEMPLATES ---
+
+// inlineTemplateKey finds a component's `template:` property where its value
+// is a template literal. templateUrl, a string value or a name that merely
+// ends in "template" do not match.
+var inlineTemplateKey = regexp.MustCompile("(?:^|[^\\w$.])template\\s*:\\s*`")
+
+// runInlineTemplates applies the Allman pass to the inline `template:`
+// literals of TypeScript components, after ESLint has run on them.
+func runInlineTemplates(files []string) {
+    if noCustomHtml {
+        return
+    }
+    optOut := angularOptOut()
+    for _, file := range files {
+        if extOf(file) != ".ts" || skipAllman(file, optOut) {
+            continue
+        }
+        applyTransform(file, "template", func(src []byte) ([]byte, error) {
+            out, err := formatInlineTemplates(string(src))
+            return []byte(out), err
+        })
+    }
+}
+
+// inlineFormatter is the Allman pass for a template embedded in a literal,
+// which never gets a final newline of its own.
+type inlineFormatter struct{}
+
+func (inlineFormatter) Format(src []byte) ([]byte, error) {
+    opts := angularOptions()
+    opts.KeepMissingNewline = true
+    out, err := angular.FormatAngularTemplate(string(src), opts)
+    return []byte(out), err
+}
+
+// formatInlineTemplates rewrites every inline template in a TypeScript
+// source. The pass sees the template as Angular does, with \` as a plain
+// backtick, and backticks are escaped again on the way back. Templates with
+// ${} expressions or other escapes are left alone, since formatting across
+// them could change what the string means. An unbalanced '}' is reported at
+// its line in the .ts file.
+func formatInlineTemplates(src string) (string, error) {
+    crlf := strings.Contains(src, "\r\n")
+    if crlf {
+        src = strings.ReplaceAll(src, "\r\n", "\n")
+    }
+
+    var f Formatter = inlineFormatter{}
+    if validateHtml {
+        f = validatingFormatter{f}
+    }
+
+    var out strings.Builder
+    pos := 0
+    for {
+        loc := inlineTemplateKey.FindStringIndex(src[pos:])
+        if loc == nil {
+            break
+        }
+        start := pos + loc[1] // just after the opening backtick
+        end, plain := templateLiteralEnd(src, start)
+        if end < 0 {
+            break
+        }
+        lineStart := strings.LastIndex(src[:start], "\n") + 1
+        out.WriteString(src[pos:start])
+        pos = end
+
+        text := src[start:end]
+        if !plain || strings.Contains(strings.ReplaceAll(text, "\\`", ""), "\\") || inComment(src[lineStart:start]) {
+            out.WriteString(text)
+            continue
+        }
+        formatted, err := formatInlineTemplate(f, strings.ReplaceAll(text, "\\`", "`"), leadingIndent(src[lineStart:start]))
+        var unbalanced *angular.UnbalancedBraceError
+        if errors.As(err, &unbalanced) {
+            return "", &angular.UnbalancedBraceError{Line: strings.Count(src[:start], "\n") + unbalanced.Line}
+        }
+        if err != nil {
+            return "", fmt.Errorf("inline template on line %d: %w", strings.Count(src[:start], "\n")+1, err)
+        }
+        out.WriteString(strings.ReplaceAll(formatted, "`", "\\`"))
+    }
+    out.WriteString(src[pos:])
+
+    result := out.String()
+    if crlf {
+        result = strings.ReplaceAll(result, "\n", "\r\n")
+    }
+    return result, nil
+}
+
+// formatInlineTemplate formats the text between a template literal's
+// backticks. In the usual layout, with the markup on its own lines between
+// the backticks, the markup's common indentation is stripped before
+// formatting and restored after, and the closing backtick keeps its line.
+// Markup that starts right after the opening backtick stays there. A
+// one-line template that the pass splits moves onto its own lines, one
+// level deeper than the line with `template:` (lineIndent).
+func formatInlineTemplate(f Formatter, text, lineIndent string) (string, error) {
+    lines := strings.Split(text, "\n")
+    leadingNewline := len(lines) > 1 && strings.TrimSpace(lines[0]) == ""
+    if leadingNewline {
+        lines = lines[1:]
+    }
+    closing := ""
+    hasClosing := len(lines) > 1 && strings.TrimSpace(lines[len(lines)-1]) == ""
+    if hasClosing {
+        closing = lines[len(lines)-1]
+        lines = lines[:len(lines)-1]
+    }
+    if strings.TrimSpace(strings.Join(lines, "")) == "" {
+        return text, nil
+    }
+
+    // The first line of "`<div>\n  ...`" has no indentation of its own;
+    // give it the same as the rest so the pass sees a consistent block
+    base := commonIndent(lines, leadingNewline)
+    if base == "" && !leadingNewline && len(lines) == 1 {
+        base = lineIndent + indentUnit
+    }
+    code := make([]string, len(lines))
+    for i, line := range lines {
+        if i == 0 && !leadingNewline {
+            code[i] = strings.TrimLeft(line, " \t")
+        } else {
+            code[i], _ = strings.CutPrefix(line, base)
+        }
+    }
+
+    formatted, err := f.Format([]byte(strings.Join(code, "\n")))
+    if err != nil {
+        return "", err
+    }
+    outLines := strings.Split(string(formatted), "\n")
+    if len(lines) == 1 && len(outLines) == 1 && !leadingNewline {
+        // Still one line: keep it where it was
+        return strings.Replace(text, strings.TrimSpace(lines[0]), outLines[0], 1), nil
+    }
+    if len(lines) == 1 && !leadingNewline && !hasClosing {
+        leadingNewline, hasClosing, closing = true, true, lineIndent
+    }
+
+    var b strings.Builder
+    if leadingNewline {
+        b.WriteString("\n")
+    }
+    for i, line := range outLines {
+        if i > 0 {
+            b.WriteString("\n")
+        }
+        if line != "" && (i > 0 || leadingNewline) {
+            line = base + line
+        }
+        b.WriteString(line)
+    }
+    if hasClosing {
+        b.WriteString("\n" + closing)
+    }
+    return b.String(), nil
+}
+
+// commonIndent is the longest run of leading whitespace shared by every
+// non-blank line, ignoring the first one unless it starts on its own line.
+func commonIndent(lines []string, includeFirst bool) string {
+    common, found := "", false
+    for i, line := range lines {
+        if strings.TrimSpace(line) == "" || (i == 0 && !includeFirst) {
+            continue
+        }
+        indent := leadingIndent(line)
+        if !found {
+            common, found = indent, true
+            continue
+        }
+        for !strings.HasPrefix(indent, common) {
+            common = common[:len(common)-1]
+        }
+    }
+    return common
+}
+
+// templateLiteralEnd scans a template literal whose text starts at i (just
+// after the opening backtick) and returns the index of the closing backtick,
+// or -1 if there is none. Escapes are skipped, and ${} expressions are
+// followed through nested braces, strings and template literals. plain
+// reports whether the literal had no ${} expression at all.
+func templateLiteralEnd(s string, i int) (end int, plain bool) {
+    plain = true
+    for ; i < len(s); i++ {
+        switch {
+        case s[i] == '\\':
+            i++
+        case s[i] == '`':
+            return i, plain
+        case s[i] == '$' && i+1 < len(s) && s[i+1] == '{':
+            plain = false
+            if i = expressionEnd(s, i+2); i < 0 {
+                return -1, false
+            }
+        }
+    }
+    return -1, false
+}
+
+// expressionEnd returns the index of the '}' that closes a ${ expression
+// whose code starts at i, or -1.
+func expressionEnd(s string, i int) int {
+    depth := 0
+    for ; i < len(s); i++ {
+        switch s[i] {
+        case '{':
+            depth++
+        case '}':
+            if depth == 0 {
+                return i
+            }
+            depth--
+        case '\'', '"':
+            quote := s[i]
+            for i++; i < len(s) && s[i] != quote && s[i] != '\n'; i++ {
+                if s[i] == '\\' {
+                    i++
+                }
+            }
+        case '`':
+            end, _ := templateLiteralEnd(s, i+1)
+            if end < 0 {
+                return -1
+            }
+            i = end
+        }
+    }
+    return -1
+}
+
+// inComment reports whether a match on this line (given the text before it)
+// is in a // or /* comment, by the usual line prefixes. A // inside a string
+// on the line, such as a URL, does not start a comment.
+func inComment(before string) bool {
+    trimmed := strings.TrimSpace(before)
+    if strings.HasPrefix(trimmed, "*") || strings.HasPrefix(trimmed, "/*") {
+        return true
+    }
+    for i := 0; i < len(before); i++ {
+        switch c := before[i]; {
+        case c == '\'' || c == '"' || c == '`':
+            for i++; i < len(before) && before[i] != c; i++ {
+                if before[i] == '\\' {
+                    i++
+                }
+            }
+        case strings.HasPrefix(before[i:], "//"):
+            return true
+        }
+    }
+    return false
+}
+
+// leadingIndent returns the spaces and tabs a line starts with.
+func leadingIndent(line string) string {
+    return line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+}
@@ -0,0 +1,72 @@
+package main
+
+import "testing"
+
+func TestFormatInlineTemplates(t *testing.T) {
+    tests := []struct {
+        name     string
+        in, want string
+    }{
+        {
+            "single line split onto its own lines",
+            "@Component({\n    selector: 'a',\n    template: `@if (a) { <p>a</p> }`,\n})\n",
+            "@Component({\n    selector: 'a',\n    template: `\n        @if (a)\n        {\n            <p>a</p>\n        }\n    `,\n})\n",
+        },
+        {
+            "single line left in place",
+            "@Component({\n    selector: 'a',\n    template: `<p>a</p>`,\n})\n",
+            "@Component({\n    selector: 'a',\n    template: `<p>a</p>`,\n})\n",
+        },
+        {
+            "multi-line",
+            "@Component({\n    selector: 'a',\n    template: `\n        <div>\n            @if (a) { <p>a</p> } @else { <p>b</p> }\n        </div>\n    `,\n})\n",
+            "@Component({\n    selector: 'a',\n    template: `\n        <div>\n            @if (a)\n            {\n                <p>a</p>\n            }\n            @else\n            {\n                <p>b</p>\n            }\n        </div>\n    `,\n})\n",
+        },
+        {
+            "url earlier on the line",
+            "@Component({ host: { 'data-doc': 'https://angular.dev' }, template: `@if (a) { <p>a</p> }` })\n",
+            "@Component({ host: { 'data-doc': 'https://angular.dev' }, template: `\n    @if (a)\n    {\n        <p>a</p>\n    }\n` })\n",
+        },
+        {
+            "commented out",
+            "@Component({\n    // template: `@if (a) { <p>a</p> }`,\n    template: `<p>x</p>`,\n})\n",
+            "@Component({\n    // template: `@if (a) { <p>a</p> }`,\n    template: `<p>x</p>`,\n})\n",
+        },
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            got, err := formatInlineTemplates(tt.in)
+            if err != nil {
+                t.Fatal(err)
+            }
+            if got != tt.want {
+                t.Fatalf("got:\n%s\nwant:\n%s", got, tt.want)
+            }
+            if again, _ := formatInlineTemplates(got); again != got {
+                t.Errorf("not idempotent, second pass gave:\n%s", again)
+            }
+        })
+    }
+}
+
+func TestInComment(t *testing.T) {
+    tests := []struct {
+        before string
+        want   bool
+    }{
+        {"    template: `", false},
+        {"    // template: `", true},
+        {" * template: `", true},
+        {"/* template: `", true},
+        {"    foo(); // template: `", true},
+        {"    url: 'https://example.com', template: `", false},
+        {`    url: "http://x/\"//", template: ` + "`", false},
+        {"    a: `//`, template: `", false},
+        {"    a: 'x', // template: `", true},
+    }
+    for _, tt := range tests {
+        if got := inComment(tt.before); got != tt.want {
+            t.Errorf("inComment(%q) = %t, want %t", tt.before, got, tt.want)
+        }
+    }
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+    "encoding/xml"
+    "fmt"
+    "os"
+    "sort"
+    "time"
+)
+
+// --- JUNIT REPORT ---
+
+// junitPath is where -junit writes the JUnit XML report ("" writes none).
+var junitPath string
+
+type junitTestSuites struct {
+    XMLName  xml.Name         `xml:"testsuites"`
+    Tests    int              `xml:"tests,attr"`
+    Failures int              `xml:"failures,attr"`
+    Errors   int              `xml:"errors,attr"`
+    Skipped  int              `xml:"skipped,attr"`
+    Time     string           `xml:"time,attr"`
+    Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+    Name     string          `xml:"name,attr"`
+    Tests    int             `xml:"tests,attr"`
+    Failures int             `xml:"failures,attr"`
+    Errors   int             `xml:"errors,attr"`
+    Skipped  int             `xml:"skipped,attr"`
+    Time     string          `xml:"time,attr"`
+    Cases    []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+    Name      string        `xml:"name,attr"`
+    ClassName string        `xml:"classname,attr"`
+    Time      string        `xml:"time,attr,omitempty"`
+    Failure   *junitMessage `xml:"failure,omitempty"`
+    Error     *junitMessage `xml:"error,omitempty"`
+    Skipped   *junitMessage `xml:"skipped,omitempty"`
+}
+
+type junitMessage struct {
+    Message string `xml:"message,attr"`
+}
+
+// writeJUnitReport writes one test case per file and tool, grouped into a
+// suite per tool. A case fails when lint errors remain or, in a dry run, when
+// the file needs formatting; a tool that could not run is an error. Times are
+// per file for the in-process passes; ESLint and Prettier run files in
+// batches, so their cases carry no time of their own.
+func writeJUnitReport(path string) {
+    resultsMu.Lock()
+    all := append([]fileResult(nil), results...)
+    resultsMu.Unlock()
+    sort.SliceStable(all, func(i, j int) bool { return all[i].path < all[j].path })
+
+    report := junitTestSuites{}
+    suiteIndex := make(map[string]int)
+    var suiteElapsed []time.Duration
+    for _, r := range all {
+        i, ok := suiteIndex[r.tool]
+        if !ok {
+            i = len(report.Suites)
+            suiteIndex[r.tool] = i
+            report.Suites = append(report.Suites, junitTestSuite{Name: "go-formatter." + r.tool})
+            suiteElapsed = append(suiteElapsed, 0)
+        }
+        suite := &report.Suites[i]
+
+        tc := junitTestCase{Name: relPath(r.path), ClassName: r.tool}
+        if r.elapsed > 0 {
+            tc.Time = junitSeconds(r.elapsed)
+            suiteElapsed[i] += r.elapsed
+        }
+        switch {
+        case r.skipped:
+            tc.Skipped = &junitMessage{Message: "deleted during run"}
+            suite.Skipped++
+        case r.err != nil:
+            tc.Error = &junitMessage{Message: r.err.Error()}
+            suite.Errors++
+        case r.errors > 0:
+            tc.Failure = &junitMessage{Message: fmt.Sprintf("%d lint error(s) remaining", r.errors)}
+            suite.Failures++
+        case r.changed && dryRun:
+            tc.Failure = &junitMessage{Message: "file needs formatting"}
+            suite.Failures++
+        }
+        suite.Tests++
+        suite.Cases = append(suite.Cases, tc)
+    }
+
+    // The overall time comes from the phase timings, which cover the batches too
+    var total time.Duration
+    for _, p := range summary.phases {
+        total += p.elapsed
+    }
+    for i := range report.Suites {
+        s := &report.Suites[i]
+        s.Time = junitSeconds(suiteElapsed[i])
+        report.Tests += s.Tests
+        report.Failures += s.Failures
+        report.Errors += s.Errors
+        report.Skipped += s.Skipped
+    }
+    report.Time = junitSeconds(total)
+
+    content, err := xml.MarshalIndent(report, "", "  ")
+    if err != nil {
+        warnf("Error encoding JUnit report: %v\n", err)
+        setExitStatus(2)
+        return
+    }
+    content = append([]byte(xml.Header), append(content, '\n')...)
+    if err := os.WriteFile(path, content, 0644); err != nil {
+        warnf("Error writing JUnit report %s: %v\n", path, err)
+        setExitStatus(2)
+        return
+    }
+    logf("JUnit report written to %s\n", path)
+}
+
+func junitSeconds(d time.Duration) string {
+    return fmt.Sprintf("%.3f", d.Seconds())
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+    "errors"
+    "fmt"
+    "io/fs"
+    "os"
+    "path/filepath"
+    "strings"
+    "sync"
+    "time"
+)
+
+// --- TOOL HOME LOCK ---
+
+// lockFileName marks toolHome as busy while one run syncs configs and installs.
+const lockFileName = ".install.lock"
+
+// The holder touches the lock every lockRefresh, however long the install
+// takes; a lock untouched for lockStaleAfter was left by a crashed process.
+const (
+    lockRefresh    = 5 * time.Second
+    lockStaleAfter = 30 * time.Second
+    lockPoll       = 500 * time.Millisecond
+)
+
+// toolHomeLock is a held lock file. release is safe to call more than once.
+type toolHomeLock struct {
+    path string
+    stop chan struct{}
+    once sync.Once
+}
+
+// installLock is the lock held during setup, so fatalf can release it
+// instead of leaving other runs to wait for it to go stale.
+var installLock *toolHomeLock
+
+// lockToolHome creates the lock file in dir, waiting up to wait for another
+// run to finish with it; 0 waits as long as the holder keeps the lock fresh,
+// matching -install-timeout 0. Two CI jobs on one machine then install one
+// after the other instead of both writing node_modules at once.
+func lockToolHome(dir string, wait time.Duration) (*toolHomeLock, error) {
+    path := filepath.Join(dir, lockFileName)
+    deadline := time.Now().Add(wait)
+    announced := false
+    for {
+        f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+        if err == nil {
+            fmt.Fprintf(f, "%d\n", os.Getpid())
+            f.Close()
+            lock := &toolHomeLock{path: path, stop: make(chan struct{})}
+            go lock.refresh()
+            return lock, nil
+        }
+        if !errors.Is(err, fs.ErrExist) {
+            return nil, err
+        }
+
+        info, statErr := os.Stat(path)
+        switch {
+        case errors.Is(statErr, fs.ErrNotExist):
+            // Released between our attempt and the stat; try again right away
+            continue
+        case statErr == nil && time.Since(info.ModTime()) > lockStaleAfter:
+            breakStaleLock(path)
+            continue
+        }
+        if wait > 0 && time.Now().After(deadline) {
+            return nil, fmt.Errorf("another run still holds %s after %s", path, wait)
+        }
+        if !announced {
+            owner, _ := os.ReadFile(path)
+            logf("Waiting for another go-formatter run (pid %s) to finish setting up %s...\n", strings.TrimSpace(string(owner)), dir)
+            announced = true
+        }
+        time.Sleep(lockPoll)
+    }
+}
+
+// breakStaleLock removes a lock left by a crashed run. Several waiters can
+// find it stale at once, so it is first renamed to a name of this run's own:
+// only one rename of the stale file succeeds, and if what was renamed turns
+// out to be a fresh lock another waiter took in the meantime, it goes back.
+func breakStaleLock(path string) {
+    aside := fmt.Sprintf("%s.stale-%d-%d", path, os.Getpid(), time.Now().UnixNano())
+    if err := os.Rename(path, aside); err != nil {
+        // Already broken (or released) by someone else
+        return
+    }
+    info, err := os.Stat(aside)
+    if err == nil && time.Since(info.ModTime()) <= lockStaleAfter {
+        // Link rather than rename back, so a lock created since isn't clobbered
+        os.Link(aside, path)
+        os.Remove(aside)
+        return
+    }
+    if err == nil {
+        warnf("Removing stale lock %s (not updated for %s).\n", path, time.Since(info.ModTime()).Round(time.Second))
+    }
+    os.Remove(aside)
+}
+
+func (l *toolHomeLock) refresh() {
+    ticker := time.NewTicker(lockRefresh)
+    defer ticker.Stop()
+    for {
+        select {
+        case <-l.stop:
+            return
+        case now := <-ticker.C:
+            os.Chtimes(l.path, now, now)
+        }
+    }
+}
+
+func (l *toolHomeLock) release() {
+    if l == nil {
+        return
+    }
+    l.once.Do(func() {
+        close(l.stop)
+        os.Remove(l.path)
+    })
+}
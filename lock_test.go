@@ -0,0 +1,84 @@
+package main
+
+import (
+    "io"
+    "os"
+    "path/filepath"
+    "sync"
+    "sync/atomic"
+    "testing"
+    "time"
+)
+
+func TestLockToolHomeWait(t *testing.T) {
+    savedOut := logOut
+    logOut = io.Discard
+    t.Cleanup(func() { logOut = savedOut })
+
+    dir := t.TempDir()
+    held, err := lockToolHome(dir, time.Second)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    if _, err := lockToolHome(dir, time.Millisecond); err == nil {
+        t.Fatal("took a lock that is still held")
+    }
+
+    // 0 means no limit: wait for the holder rather than give up at once
+    time.AfterFunc(2*lockPoll, held.release)
+    lock, err := lockToolHome(dir, 0)
+    if err != nil {
+        t.Fatalf("lockToolHome(dir, 0): %v", err)
+    }
+    lock.release()
+    if _, err := os.Stat(filepath.Join(dir, lockFileName)); !os.IsNotExist(err) {
+        t.Errorf("lock file left behind after release: %v", err)
+    }
+}
+
+// TestStaleLockBrokenOnce checks that waiters which all find the same lock
+// stale still take it one at a time.
+func TestStaleLockBrokenOnce(t *testing.T) {
+    savedOut := logOut
+    logOut = io.Discard
+    t.Cleanup(func() { logOut = savedOut })
+
+    dir := t.TempDir()
+    path := filepath.Join(dir, lockFileName)
+    if err := os.WriteFile(path, []byte("1\n"), 0644); err != nil {
+        t.Fatal(err)
+    }
+    old := time.Now().Add(-2 * lockStaleAfter)
+    if err := os.Chtimes(path, old, old); err != nil {
+        t.Fatal(err)
+    }
+
+    var holders, overlaps atomic.Int32
+    var wg sync.WaitGroup
+    for range 4 {
+        wg.Add(1)
+        go func() {
+            defer wg.Done()
+            lock, err := lockToolHome(dir, 10*time.Second)
+            if err != nil {
+                t.Error(err)
+                return
+            }
+            if holders.Add(1) > 1 {
+                overlaps.Add(1)
+            }
+            time.Sleep(100 * time.Millisecond)
+            holders.Add(-1)
+            lock.release()
+        }()
+    }
+    wg.Wait()
+
+    if n := overlaps.Load(); n > 0 {
+        t.Errorf("%d waiter(s) held the lock at the same time as another", n)
+    }
+    if left, _ := filepath.Glob(filepath.Join(dir, "*")); len(left) > 0 {
+        t.Errorf("files left behind: %v", left)
+    }
+}
@@ -1,15 +1,30 @@
 package main
 
 import (
+    "bufio"
+    "bytes"
+    "crypto/sha256"
     "embed"
+    "encoding/json"
+    "errors"
     "flag"
     "fmt"
+    "io"
     "log"
+    "maps"
+    "net/url"
     "os"
     "os/exec"
     "path/filepath"
     "runtime"
+    "slices"
+    "strconv"
     "strings"
+    "sync"
+    "sync/atomic"
+    "time"
+
+    "formatter/angular"
 )
 
 // --- EMBEDDED CONFIGURATION ---
@@ -21,523 +36,2161 @@ var configFiles embed.FS
 var repoPath string
 var toolHome string 
 
-func main() {
-    var inputPath string
-    flag.StringVar(&inputPath, "path", ".", "Path to the git repository")
-    flag.Parse()
+// toolHomeIsDefault is set when neither -tool-home nor $INSIPP_TOOL_HOME chose
+// the tool home; only then may an unwritable one be swapped for a temp directory.
+var toolHomeIsDefault bool
 
-    //  Setup Repo Path
-    absPath, err := filepath.Abs(inputPath)
-    if err != nil {
-        log.Fatalf("Error resolving path: %v", err)
-    }
-    repoPath = absPath
-    if _, err := os.Stat(repoPath); os.IsNotExist(err) {
-        log.Fatalf("Directory does not exist: %s", repoPath)
-    }
+// toolHomeEnv overrides the default tool home when -tool-home is not given.
+const toolHomeEnv = "INSIPP_TOOL_HOME"
 
-    fmt.Printf("Operating in: %s\n", repoPath)
+// readOnly guarantees that nothing on disk is modified and nothing is installed.
+var readOnly bool
 
-    // Setup the Linter Environment
-    setupToolEnvironment()
+// dryRun reports what would change without writing. Implied by readOnly.
+var dryRun bool
 
-    // Git Logic
-    currentBranch := getCommandOutput("git", "branch", "--show-current")
-    if currentBranch == "" {
-        log.Fatalf("Could not detect current branch.")
-    }
+// packageManager is the installer used for the tool's Node dependencies.
+var packageManager string
 
-    parentBranch := findForkPoint(currentBranch)
-    if !isValidRef(parentBranch) {
-        fmt.Printf("Parent '%s' not found. Falling back to 'main'.\n", parentBranch)
-        parentBranch = "main"
-    }
+// offline forbids dependency installs; binaries must already be in toolHome.
+var offline bool
 
-    fmt.Printf("Calculating changes: %s...%s\n", parentBranch, currentBranch)
+// noInstall leaves toolHome exactly as it is (-no-install): unlike offline,
+// the configs are not re-synced either, and the format cache is not saved.
+// Everything must already be provisioned.
+var noInstall bool
 
-    cmd := exec.Command("git", "diff", "--name-only", fmt.Sprintf("%s...HEAD", parentBranch))
-    cmd.Dir = repoPath
-    output, err := cmd.CombinedOutput()
-    if err != nil {
-        log.Fatalf("Error running git diff: %v", err)
-    }
+// npmRegistry is the registry dependencies are installed from (-npm-registry,
+// default $NPM_CONFIG_REGISTRY). Empty leaves the package manager's default.
+var npmRegistry string
 
-    // 4. Run the processors
-    processChanges(string(output))
-}
+// npmrcPath points the package manager at a specific .npmrc (-npmrc), e.g.
+// one holding the auth token for a corporate registry.
+var npmrcPath string
 
-// --- TOOL ENVIRONMENT SETUP ---
+// eslintConfig overrides the embedded ESLint config when set.
+var eslintConfig string
 
-func setupToolEnvironment() {
-    homeDir, err := os.UserHomeDir()
-    if err != nil {
-        log.Fatalf("Could not find user home directory: %v", err)
-    }
+// prettierConfig overrides the embedded .prettierrc when set.
+var prettierConfig string
 
-    toolHome = filepath.Join(homeDir, ".insipp-linter-tool")
-    if err := os.MkdirAll(toolHome, 0755); err != nil {
-        log.Fatalf("Failed to create tool directory: %v", err)
-    }
+// maxWarnings fails the run when ESLint reports more warnings in total
+// (-max-warnings). Negative means no limit.
+var maxWarnings int
 
-    // Helper to extract embedded files to the user's disk
-    extractFile := func(embedPath, destName string) {
-        content, err := configFiles.ReadFile(embedPath)
-        if err != nil {
-            log.Fatalf("Failed to read embedded config %s: %v", embedPath, err)
-        }
-        destPath := filepath.Join(toolHome, destName)
-        if err := os.WriteFile(destPath, content, 0644); err != nil {
-            log.Fatalf("Failed to write config %s: %v", destName, err)
-        }
-    }
+// preferLocal uses the project's own ESLint and Prettier when it has both (-prefer-local).
+var preferLocal bool
 
-    // Always overwrite configs to keep them up to date with the binary
-    extractFile("configs/eslint.config.mjs", "eslint.config.mjs")
-    extractFile("configs/.prettierrc", ".prettierrc")
+// useLocalTools is set by setupToolEnvironment when -prefer-local found both
+// tools under repoPath/node_modules. Their binaries and the project's own
+// configs are then used instead of toolHome and the embedded ones.
+var useLocalTools bool
 
-    // Check if we need to install/update dependencies
-    pkgDest := filepath.Join(toolHome, "package.json")
-    prettierBin := filepath.Join(toolHome, "node_modules", ".bin", "prettier")
-    if runtime.GOOS == "windows" {
-        prettierBin += ".cmd"
+// verbose logs every external command and fork-point decision to stderr.
+var verbose bool
+
+// skipGlobs excludes matching repo-relative paths before routing (-skip-glob).
+var skipGlobs stringList
+
+// skipGlobsDir is the directory skipGlobs match relative to: that of the
+// config file they came from, which may be a parent of repoPath. Empty for
+// -skip-glob, which is relative to repoPath.
+var skipGlobsDir string
+
+// skipGlobPath is file as skipGlobs see it, in slash form.
+func skipGlobPath(file string) string {
+    if skipGlobsDir == "" {
+        return relPath(file)
     }
+    rel, err := filepath.Rel(skipGlobsDir, file)
+    if err != nil {
+        return relPath(file)
+    }
+    return filepath.ToSlash(rel)
+}
 
-    _, pkgErr := os.Stat(pkgDest)
-    _, binErr := os.Stat(prettierBin)
+// onlyGlobs narrows the file set to paths matching at least one pattern (-only).
+var onlyGlobs stringList
 
-    needsInstall := os.IsNotExist(pkgErr) || os.IsNotExist(binErr)
+// listOnly prints the routed files instead of formatting them (-list).
+var listOnly bool
 
-    if needsInstall {
-        fmt.Println("Updating linter environment (installing Prettier/ESLint)...")
+// assumeYes answers yes to confirmation prompts (-yes).
+var assumeYes bool
 
-        // Write package.json only when installing to trigger updates if needed
-        extractFile("configs/package.json", "package.json")
+// fromStdin reads the files to process from standard input (-stdin).
+var fromStdin bool
 
-        npmCmd := "npm"
-        if runtime.GOOS == "windows" {
-            npmCmd = "npm.cmd"
-        }
+// failOnChange exits 1 when any file had to be rewritten (-fail-on-change).
+var failOnChange bool
 
-        cmd := exec.Command(npmCmd, "install")
-        cmd.Dir = toolHome
-        cmd.Stdout = os.Stdout
-        cmd.Stderr = os.Stderr
+// exitStatus is the process exit code; the worst result seen wins.
+var exitStatus int
+var exitMu sync.Mutex
 
-        if err := cmd.Run(); err != nil {
-            log.Fatalf("Failed to install linter dependencies: %v", err)
-        }
-        fmt.Println("Tool environment ready.")
+func main() {
+    var inputPath string
+    var diffOpts diffOptions
+    var explicitFiles stringList
+    flag.BoolVar(&showVersion, "version", false, "Print the tool version, Go version and embedded config hash, then exit")
+    flag.BoolVar(&printConfig, "print-config", false, "Print the effective configuration (tool home, package manager, extension routing, exclusions, indent, base branch), then exit")
+    flag.StringVar(&inputPath, "path", ".", "Path to the git repository")
+    flag.BoolVar(&diffOpts.staged, "staged", false, "Only process files staged in the git index (for pre-commit hooks)")
+    flag.StringVar(&diffOpts.since, "since", "", "Diff from this ref instead of the detected parent branch")
+    flag.StringVar(&diffOpts.until, "until", "", "End of the -since range (default HEAD)")
+    flag.StringVar(&diffOpts.base, "base", "", "Compare against this ref instead of detecting the parent branch (e.g. origin/main on a detached CI checkout)")
+    flag.StringVar(&diffOpts.base, "base-branch", "", "Same as -base: use this parent branch (e.g. release/current) and skip fork-point detection")
+    flag.StringVar(&diffOpts.between, "between", "", "Process files changed between two refs, e.g. v1.2.0..v1.3.0 (HEAD is not involved)")
+    flag.BoolVar(&diffOpts.all, "all", false, "Process every tracked file (git ls-files) instead of a diff; asks for confirmation unless -yes")
+    flag.BoolVar(&diffOpts.working, "working-tree", false, "Process uncommitted changes: unstaged, staged and untracked (not ignored) files")
+    diffArgsFlag := flag.String("diff-args", "", "Extra options for the git diff that selects files, e.g. '-w --find-renames=40%'")
+    flag.BoolVar(&diffOpts.fetch, "fetch", false, "Fetch the parent branch first when it is a remote-tracking ref (e.g. origin/main)")
+    flag.BoolVar(&assumeYes, "yes", false, "Don't ask for confirmation (e.g. for -all)")
+    flag.Var(&explicitFiles, "file", "File to process, bypassing git detection (repeatable)")
+    flag.BoolVar(&fromStdin, "stdin", false, "Read newline-separated file paths from stdin, bypassing git detection (e.g. for lint-staged)")
+    flag.BoolVar(&initRepo, "init", false, "Write the embedded ESLint/Prettier configs and a starter .go-formatter.yaml into -path, then exit")
+    flag.BoolVar(&forceInit, "force", false, "With -init, overwrite existing files without asking")
+    configFile := flag.String("config-file", "", "Settings file to read (default: .go-formatter.yaml/.yml/.json in -path or a parent up to the git root)")
+    flag.BoolVar(&validateHtml, "validate-html", false, "Check that the custom HTML pass kept every tag (and its attributes) in order; leave the file unchanged if not")
+    flag.StringVar(&braceStyle, "brace-style", braceAllman, "Where the custom HTML pass puts a control flow block's '{': 'allman' (own line) or 'k&r' (end of the @if/@for line)")
+    indentFlag := flag.String("indent", "4", "Indent per brace level for the custom HTML pass: a number of spaces or 'tab'")
+    flag.IntVar(&maxBlankLines, "max-blank-lines", 1, "Collapse longer runs of blank lines in HTML templates to this many (-1 keeps them all)")
+    flag.BoolVar(&finalNewline, "final-newline", true, "Make the custom HTML pass end every template with a newline (-final-newline=false keeps a missing one missing)")
+    flag.StringVar(&serveAddr, "serve", "", "Run a formatting server for editors on this localhost address or unix:/path socket instead of processing files")
+    flag.BoolVar(&watch, "watch", false, "After the first run, keep watching the repo and re-format files when they are saved")
+    flag.BoolVar(&noCache, "no-cache", false, "Process every file even if it is unchanged since its last successful format")
+    flag.BoolVar(&quiet, "quiet", false, "Only print warnings, errors and files that fail or would change")
+    flag.BoolVar(&verbose, "verbose", false, "Log every git/ESLint/Prettier/install command before running it")
+    flag.Var(&onlyGlobs, "only", "Only process files matching this gitignore-style glob, e.g. 'src/app/**/*.component.html' (repeatable)")
+    flag.Var(&skipGlobs, "skip-glob", "Exclude files matching this gitignore-style glob, e.g. 'deploy/**/*.yaml' (repeatable)")
+    var mapExts stringList
+    flag.Var(&mapExts, "map-ext", "Route an extra extension to a handler, e.g. .cshtml=prettier (repeatable; handlers: eslint, html, style, data, markup, prettier, markdown)")
+    var excludeExts stringList
+    flag.Var(&excludeExts, "exclude-ext", "Never format files with this extension, e.g. .html (repeatable)")
+    flag.BoolVar(&includeGenerated, "include-generated", false, "Also process files under node_modules/, dist/ and .angular/ at the repo root")
+    flag.BoolVar(&readOnly, "read-only", false, "Inspect only: never write files, never install dependencies")
+    flag.BoolVar(&listOnly, "list", false, "Print the files that would be processed, grouped by tool, without running any formatter")
+    checkOnly := flag.Bool("check-only", false, "CI gate: never write files; exit 0 if everything is formatted, 1 if something needs formatting, 2 if a tool failed")
+    flag.BoolVar(&noCustomHtml, "no-custom-html", false, "Run only Prettier on HTML files and skip the custom Allman brace pass")
+    flag.BoolVar(&sortImports, "sort-imports", false, "After ESLint, sort the import block of TS files into external, internal and relative groups")
+    flag.BoolVar(&backupFiles, "backup", false, "Copy every HTML file to a per-repo temp directory before formatting it; the copies are removed after a successful run")
+    flag.BoolVar(&restoreBackups, "restore", false, "Put back the files saved by -backup (after a failed run or with -keep-backups), then exit")
+    flag.BoolVar(&keepBackups, "keep-backups", false, "Keep the -backup copies after a successful run (and after -restore)")
+    flag.BoolVar(&verifyIdempotent, "verify-idempotent", false, "After the HTML pipeline, run it again in memory and fail files whose output would change a second time")
+    flag.BoolVar(&failOnChange, "fail-on-change", false, "Write fixes as usual, but exit 1 if any file was modified")
+    flag.BoolVar(&dryRun, "dry-run", false, "Report files that would change without writing them; exits 1 if any would")
+    flag.StringVar(&sarifPath, "sarif", "", "Also write a SARIF 2.1.0 report of ESLint messages (and, in a dry run, unformatted files) for code scanning")
+    flag.BoolVar(&useDifftool, "difftool", false, "With -dry-run, open each change from the custom passes in the configured git difftool instead of printing a diff")
+    flag.StringVar(&junitPath, "junit", "", "Also write a JUnit XML report to this file (one test case per file and tool)")
+    flag.StringVar(&outputFormat, "format", "text", "Output format: 'text' for the human-readable report, 'json' for a JSON report on stdout")
+    flag.BoolVar(&onlyErrors, "only-errors", false, "Only list files with remaining errors or failures in the final report")
+    flag.IntVar(&jobs, "jobs", runtime.NumCPU(), "Number of ESLint/Prettier processes to run concurrently")
+    flag.BoolVar(&perFile, "per-file", false, "Run ESLint/Prettier once per file and print '=== path ===' before each file's output")
+    flag.IntVar(&memBudget, "mem-budget", 0, "Soft memory budget in MB for concurrent ESLint/Prettier processes (0 = unlimited)")
+    flag.StringVar(&eslintConfig, "eslint-config", "", "ESLint config to use instead of the embedded one (relative paths resolve against -path)")
+    flag.StringVar(&prettierConfig, "prettier-config", "", "Prettier config to use instead of the embedded one (relative paths resolve against -path)")
+    flag.IntVar(&maxWarnings, "max-warnings", -1, "Exit 1 if ESLint reports more than this many warnings in total (-1 = no limit)")
+    flag.BoolVar(&preferLocal, "prefer-local", false, "Use the project's node_modules/.bin/eslint and prettier with the project's configs when both are installed")
+    flag.StringVar(&toolHome, "tool-home", "", "Directory for extracted configs and node_modules (default $"+toolHomeEnv+" or ~/.insipp-linter-tool)")
+    flag.IntVar(&installRetries, "install-retries", 3, "Attempts for the dependency install when it fails with a network error (backoff 2s, 4s, ...)")
+    flag.DurationVar(&installTimeout, "install-timeout", 10*time.Minute, "Kill a dependency install attempt that runs longer than this (0 = no limit)")
+    flag.DurationVar(&toolTimeout, "timeout", 5*time.Minute, "Kill any git/ESLint/Prettier invocation that runs longer than this (0 = no limit)")
+    flag.StringVar(&npmRegistry, "npm-registry", os.Getenv("NPM_CONFIG_REGISTRY"), "Registry URL for installing the tool's dependencies (default $NPM_CONFIG_REGISTRY)")
+    flag.StringVar(&npmrcPath, "npmrc", "", "Use this .npmrc for installs (registry, auth token, proxy settings)")
+    flag.BoolVar(&offline, "offline", false, "Never run the package manager; fail if ESLint/Prettier are not already installed")
+    flag.BoolVar(&noInstall, "no-install", false, "Never write to the tool home (no config sync, no install, no cache); fail if anything in it is missing or outdated")
+    flag.StringVar(&packageManager, "package-manager", "", "Installer for the tool's Node dependencies: npm, yarn or pnpm (default: auto-detect)")
+    flag.Parse()
+
+    if showVersion {
+        printVersion()
+        os.Exit(0)
     }
-}
 
-// --- FILE PROCESSING ---
+    switch outputFormat {
+    case "text":
+    case "json":
+        logOut = os.Stderr
+    default:
+        fatalf("Unknown -format %q (expected text or json)", outputFormat)
+    }
+    useColor = colorSupported(logOut)
 
-func processChanges(rawOutput string) {
-    lines := strings.Split(strings.TrimSpace(rawOutput), "\n")
+    //  Setup Repo Path
+    absPath, err := filepath.Abs(inputPath)
+    if err != nil {
+        fatalf("Error resolving path: %v", err)
+    }
+    repoPath = absPath
+    if _, err := os.Stat(repoPath); os.IsNotExist(err) {
+        fatalf("Directory does not exist: %s", repoPath)
+    }
 
-    var eslintFiles []string
-    var htmlFiles []string
+    logf("Operating in: %s\n", repoPath)
 
-    for _, f := range lines {
-        f = strings.TrimSpace(f)
-        if f == "" {
-            continue
+    if initRepo {
+        if readOnly {
+            fatalf("-init writes files into the repository and cannot be combined with -read-only.")
         }
-        fullPath := filepath.Join(repoPath, f)
+        runInit()
+        os.Exit(exitStatus)
+    }
 
-        if _, err := os.Stat(fullPath); os.IsNotExist(err) {
-            continue
+    // Settings file: fills in everything not given on the command line
+    configPath := findConfigFile(repoPath)
+    if *configFile != "" {
+        configPath = resolveRepoPath(*configFile)
+    }
+    if configPath != "" {
+        cfg, err := loadConfigFile(configPath)
+        if err != nil {
+            fatalf("Error reading config file %s: %v", configPath, err)
         }
-
-        ext := strings.ToLower(filepath.Ext(f))
-
-        switch ext {
-        case ".js", ".jsx", ".ts", ".tsx", ".mjs", ".cjs":
-            eslintFiles = append(eslintFiles, fullPath)
-        case ".html":
-            htmlFiles = append(htmlFiles, fullPath)
+        if err := applyConfig(cfg, indentFlag); err != nil {
+            fatalf("Invalid config file %s: %v", configPath, err)
+        }
+        logf("Using settings from: %s\n", configPath)
+    }
+    // After the config file, so flags win for the same extension
+    for _, mapping := range mapExts {
+        ext, tool, ok := strings.Cut(mapping, "=")
+        if !ok {
+            fatalf("-map-ext expects EXT=HANDLER (e.g. .cshtml=prettier), got %q", mapping)
+        }
+        if err := routeExtension(strings.TrimSpace(ext), strings.TrimSpace(tool)); err != nil {
+            fatalf("Invalid -map-ext: %v", err)
         }
     }
+    for _, ext := range excludeExts {
+        excludedExts[normalizeExt(ext)] = true
+    }
+    if len(excludedExts) > 0 {
+        verbosef("Disabled extensions (-exclude-ext): %s", strings.Join(slices.Sorted(maps.Keys(excludedExts)), ", "))
+    }
 
-    if len(eslintFiles) > 0 {
-        runEslint(eslintFiles)
+    if readOnly || *checkOnly {
+        dryRun = true
+    }
+    if indent, err := angular.ParseIndent(*indentFlag); err != nil {
+        fatalf("Invalid -indent: %v", err)
     } else {
-        fmt.Println("No JS/TS files to lint.")
+        indentUnit = indent
     }
-
-    if len(htmlFiles) > 0 {
-        runHtmlProcessing(htmlFiles)
+    switch strings.ToLower(braceStyle) {
+    case braceAllman:
+    case braceKR, "kr":
+        braceStyle = braceKR
+    default:
+        fatalf("Unknown -brace-style %q (expected allman or k&r)", braceStyle)
+    }
+    if jobs < 1 {
+        fatalf("-jobs must be at least 1, got %d", jobs)
+    }
+    if installRetries < 1 {
+        fatalf("-install-retries must be at least 1, got %d", installRetries)
+    }
+    if memBudget < 0 {
+        fatalf("-mem-budget must not be negative, got %d", memBudget)
+    }
+    if packageManager == "" {
+        packageManager = detectPackageManager()
+    } else if !slices.Contains(packageManagers, packageManager) {
+        fatalf("Unknown -package-manager %q (expected npm, yarn or pnpm)", packageManager)
+    }
+    if npmRegistry != "" {
+        if u, err := url.Parse(npmRegistry); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+            fatalf("Invalid -npm-registry %q: expected an http(s) URL such as https://npm.example.com/", npmRegistry)
+        }
+    }
+    if npmrcPath != "" {
+        npmrcPath = resolveRepoPath(npmrcPath)
+        if info, err := os.Stat(npmrcPath); err != nil || info.IsDir() {
+            fatalf("-npmrc file not found: %s", npmrcPath)
+        }
+    }
+    if modes := diffOpts.modes(); len(modes) > 1 {
+        fatalf("Conflicting diff modes: %s. Pick only one.", strings.Join(modes, ", "))
+    }
+    if useDifftool && !dryRun {
+        fatalf("-difftool only previews changes; combine it with -dry-run or -check-only.")
+    }
+    if extra, err := parseDiffArgs(*diffArgsFlag); err != nil {
+        fatalf("Invalid -diff-args: %v", err)
     } else {
-        fmt.Println("No HTML files to process.")
+        diffOpts.extra = extra
     }
-}
 
-func runEslint(files []string) {
-    fmt.Printf("Running ESLint --fix on %d file(s)...\n", len(files))
-
-    eslintBin := filepath.Join(toolHome, "node_modules", ".bin", "eslint")
-    if runtime.GOOS == "windows" {
-        eslintBin += ".cmd"
+    // Fail fast instead of letting Prettier silently fall back to its defaults
+    if prettierConfig != "" {
+        if _, err := os.Stat(resolveRepoPath(prettierConfig)); err != nil {
+            fatalf("Prettier config not found: %s", resolveRepoPath(prettierConfig))
+        }
     }
 
-    configPath := filepath.Join(toolHome, "eslint.config.mjs")
-    args := []string{"--config", configPath, "--fix"}
-    args = append(args, files...)
-
-    cmd := exec.Command(eslintBin, args...)
-    cmd.Dir = repoPath
-    cmd.Stdout = os.Stdout
-    cmd.Stderr = os.Stderr
+    if printConfig {
+        resolveToolHome()
+        printEffectiveConfig(configPath, diffOpts)
+        os.Exit(exitStatus)
+    }
 
-    if err := cmd.Run(); err != nil {
-        fmt.Println("\nESLint finished with issues (or fixed code).")
-    } else {
-        fmt.Println("\nESLint finished successfully.")
+    if restoreBackups {
+        runRestore()
+        os.Exit(exitStatus)
     }
-}
 
-func runHtmlProcessing(files []string) {
-    fmt.Printf("Processing %d HTML file(s) (Prettier + Allman Braces)...\n", len(files))
+    exitOnInterrupt()
 
-    // 1. Run Prettier First
-    prettierBin := filepath.Join(toolHome, "node_modules", ".bin", "prettier")
-    if runtime.GOOS == "windows" {
-        prettierBin += ".cmd"
+    // Server mode formats what editors send; it never looks at git
+    if serveAddr != "" {
+        setupToolEnvironment()
+        serve(serveAddr)
+        os.Exit(exitStatus)
     }
 
-    configPath := filepath.Join(toolHome, ".prettierrc")
-    
-    args := []string{"--write", "--config", configPath}
-    args = append(args, files...)
-
-    cmd := exec.Command(prettierBin, args...)
-    cmd.Dir = repoPath
-    cmd.Stdout = os.Stdout
-    cmd.Stderr = os.Stderr
+    // Git Logic - skipped when only explicit files were given
+    useGit := (len(explicitFiles) == 0 && !fromStdin) || len(diffOpts.modes()) > 0
+    if useGit {
+        checkGit()
+    }
 
-    if err := cmd.Run(); err != nil {
-        fmt.Printf("Prettier encountered a warning/error (continuing to custom formatting): %v\n", err)
+    // Setup the Linter Environment - listing runs no tools, so it needs no install
+    if listOnly {
+        resolveToolHome()
+    } else {
+        setupToolEnvironment()
     }
 
-    // Process each file with custom formatting
-    for _, file := range files {
-        content, err := os.ReadFile(file)
-        if err != nil {
-            fmt.Printf("Error reading %s: %v\n", file, err)
-            continue
+    // Explicitly requested files (resolved against the repo)
+    var files []string
+    for _, f := range explicitFiles {
+        fullPath := resolveRepoPath(f)
+        if _, err := os.Stat(fullPath); err != nil {
+            fatalf("File does not exist: %s", fullPath)
         }
+        files = append(files, fullPath)
+    }
+    if fromStdin {
+        files = append(files, readFileList(os.Stdin)...)
+    }
 
-        contentStr := string(content)
-        newContent := formatAngularTemplate(contentStr)
+    if useGit {
+        files = append(files, scopeToPath(gitChangedFiles(diffOpts))...)
+    }
 
-        if newContent != contentStr {
-            if err := os.WriteFile(file, []byte(newContent), 0644); err != nil {
-                fmt.Printf("Error writing %s: %v\n", file, err)
-            }
-        }
+    // 4. Run the processors
+    processChanges(files)
+    if listOnly {
+        os.Exit(0)
+    }
+    printResults()
+    if junitPath != "" {
+        writeJUnitReport(junitPath)
+    }
+    if sarifPath != "" {
+        writeSarifReport(sarifPath)
     }
-    fmt.Println("HTML processing finished.")
-}
 
-// Replace your existing formatAngularTemplate function with this implementation.
-// This properly handles:
-// - Nested parentheses like adminTypes()
-// - @else and @else if patterns
-// - Multiple closing braces on one line (} } or } } })
-// - Preserves {{ }} interpolation
-// - Preserves HTML comments
+    if watch {
+        watchRepo()
+    }
 
-const indentUnit = "    " // 4 spaces - adjust if you use tabs or different spacing
+    haltIfInterrupted()
+    finishBackups()
+    os.Exit(exitStatus)
+}
 
+// fatalf reports an error that stops the run. It exits 2 like any other tool
+// failure, so CI can tell a broken setup apart from files needing formatting.
+func fatalf(format string, args ...any) {
+    haltIfInterrupted()
+    log.Printf(format, args...)
+    installLock.release()
+    os.Exit(2)
+}
 
+// setExitStatus records a failure code without downgrading a worse one.
+// It is safe to call from concurrent workers.
+func setExitStatus(code int) {
+    exitMu.Lock()
+    defer exitMu.Unlock()
+    if code > exitStatus {
+        exitStatus = code
+    }
+}
 
-func formatAngularTemplate(content string) string {
-    lines := strings.Split(content, "\n")
-    var result []string
+// --- GIT DIFF MODES ---
 
-    depth := 0
-    inComment := false
+// checkGit fails fast when git itself is unusable or repoPath is not inside a
+// work tree; otherwise every git query would quietly come back empty.
+func checkGit() {
+    if _, err := runner.Output("git", "--version"); err != nil {
+        fatalf("git is not available (%v). Install git and make sure it is on PATH.", err)
+    }
 
-    for _, originalLine := range lines {
-        trimmed := strings.TrimSpace(originalLine)
-        originalIndent := extractIndent(originalLine)
+    out, err := runner.Output("git", "rev-parse", "--is-inside-work-tree")
+    if err != nil || strings.TrimSpace(string(out)) != "true" {
+        fatalf("%s is not inside a git work tree. Run from a repository, point -path at one, or pass files with -file.", repoPath)
+    }
 
-        if trimmed == "" {
-            result = append(result, "")
-            continue
-        }
+    // Inside a submodule this is the submodule's own root, not the superproject's
+    gitRoot = getCommandOutput("git", "rev-parse", "--show-toplevel")
+    if gitRoot == "" {
+        fatalf("Could not determine the git top-level directory for %s.", repoPath)
+    }
+    gitRoot = filepath.FromSlash(gitRoot)
+    if gitRoot != repoPath {
+        verbosef("Git top-level is %s; only files under %s are processed.", gitRoot, repoPath)
+    }
+}
 
-        // Track multi-line HTML comments - preserve exactly
-        if strings.Contains(trimmed, "<!--") && !strings.Contains(trimmed, "-->") {
-            inComment = true
-            result = append(result, originalLine)
-            continue
-        }
-        if inComment {
-            result = append(result, originalLine)
-            if strings.Contains(trimmed, "-->") {
-                inComment = false
-            }
+// gitRoot is the top-level directory of the work tree containing repoPath.
+// git prints diff paths relative to it, even when repoPath is a subdirectory.
+var gitRoot string
+
+// scopeToPath turns top-level-relative git paths into paths under repoPath
+// and drops the ones outside it, so -path can narrow a run to one part of a
+// larger repository.
+func scopeToPath(paths []string) []string {
+    // git reports the real path; repoPath may go through a symlink
+    realRepo, realRoot := resolveSymlinks(repoPath), resolveSymlinks(gitRoot)
+
+    var scoped []string
+    for _, p := range paths {
+        rel, err := filepath.Rel(realRepo, filepath.Join(realRoot, filepath.FromSlash(p)))
+        if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
             continue
         }
+        scoped = append(scoped, filepath.Join(repoPath, rel))
+    }
+    if dropped := len(paths) - len(scoped); dropped > 0 {
+        logf("Limited to %s: %d of %d changed file(s) are outside it.\n", repoPath, dropped, len(paths))
+    }
+    return scoped
+}
 
-        // Check if this line needs expansion
-        needsExpand := (strings.Contains(trimmed, "@") && isControlFlowLine(trimmed)) ||
-            strings.Contains(trimmed, "} }")
-
-        if !needsExpand {
-            // Check for standalone }
-            if trimmed == "}" {
-                depth--
-                if depth < 0 {
-                    depth = 0
-                }
-                extraIndent := strings.Repeat(indentUnit, depth)
-                result = append(result, extraIndent+originalIndent+trimmed)
-                continue
-            }
+// resolveSymlinks returns the real path of p, or p itself if it can't be resolved.
+func resolveSymlinks(p string) string {
+    if real, err := filepath.EvalSymlinks(p); err == nil {
+        return real
+    }
+    return p
+}
 
-            // Regular line - add depth-based indent
-            extraIndent := strings.Repeat(indentUnit, depth)
-            result = append(result, extraIndent+originalIndent+trimmed)
-            continue
-        }
+// diffOptions selects which set of changes the git diff is computed over.
+// The zero value diffs the current branch against its detected parent.
+type diffOptions struct {
+    staged  bool
+    between string
+    since   string
+    until   string
+    base    string   // parent ref for the default mode instead of detecting one
+    all     bool     // every tracked file instead of a diff
+    working bool     // uncommitted changes: unstaged, staged and untracked
+    fetch   bool     // update a remote-tracking parent before diffing against it
+    extra   []string // -diff-args, already validated by parseDiffArgs
+}
 
-        // Expand this line
-        expanded := expandLineWithIndent(trimmed, originalIndent, depth)
+// allowedDiffArgs are the git diff options -diff-args may pass through: the
+// ones that change which files count as changed (whitespace, renames, the
+// diff algorithm). Anything else, such as a ref, a pathspec or --output,
+// would change what the tool does rather than how the diff is computed.
+var allowedDiffArgs = []string{
+    "-w", "--ignore-all-space", "-b", "--ignore-space-change", "--ignore-space-at-eol",
+    "--ignore-cr-at-eol", "--ignore-blank-lines", "-I", "--ignore-matching-lines",
+    "-M", "--find-renames", "--no-renames", "-C", "--find-copies", "--find-copies-harder",
+    "-B", "--break-rewrites", "-l",
+    "--diff-algorithm", "--minimal", "--patience", "--histogram", "--anchored",
+    "--ignore-submodules",
+}
 
-        for _, expLine := range expanded.lines {
-            result = append(result, expLine)
+// parseDiffArgs splits a -diff-args value on whitespace and rejects anything
+// that isn't one of allowedDiffArgs, with or without a value ("-M50%",
+// "--find-renames=40%", "--diff-algorithm=histogram").
+func parseDiffArgs(value string) ([]string, error) {
+    args := strings.Fields(value)
+    for _, arg := range args {
+        if !slices.ContainsFunc(allowedDiffArgs, func(allowed string) bool { return diffArgMatches(arg, allowed) }) {
+            return nil, fmt.Errorf("%q is not an allowed git diff option (allowed: %s)", arg, strings.Join(allowedDiffArgs, " "))
         }
-
-        depth = expanded.finalDepth
     }
-
-    return strings.Join(result, "\n")
+    return args, nil
 }
 
-type expandResult struct {
-    lines      []string
-    finalDepth int
-}
-
-func isControlFlowLine(trimmed string) bool {
-    if (strings.Contains(trimmed, "@for") || strings.Contains(trimmed, "@if") ||
-        strings.Contains(trimmed, "@else") || strings.Contains(trimmed, "@switch")) &&
-        strings.Contains(trimmed, "{") {
+func diffArgMatches(arg, allowed string) bool {
+    if arg == allowed {
         return true
     }
-    if strings.Contains(trimmed, "} @") {
-        return true
+    if strings.HasPrefix(allowed, "--") {
+        return strings.HasPrefix(arg, allowed+"=")
     }
-    return false
+    // Short options that take a value have it attached: -M50%, -I^//
+    return slices.Contains([]string{"-M", "-C", "-B", "-I", "-l"}, allowed) && strings.HasPrefix(arg, allowed)
 }
 
-func expandLineWithIndent(trimmed, originalIndent string, startDepth int) expandResult {
-    var result []string
-    var currentLine strings.Builder
-
-    depth := startDepth
-    localDepth := 0
-
-    i := 0
-    for i < len(trimmed) {
-        ch := trimmed[i]
+// modes lists the explicitly selected diff modes by flag name.
+func (o diffOptions) modes() []string {
+    var modes []string
+    if o.staged {
+        modes = append(modes, "-staged")
+    }
+    if o.between != "" {
+        modes = append(modes, "-between")
+    }
+    if o.since != "" || o.until != "" {
+        modes = append(modes, "-since/-until")
+    }
+    if o.base != "" {
+        modes = append(modes, "-base/-base-branch")
+    }
+    if o.all {
+        modes = append(modes, "-all")
+    }
+    if o.working {
+        modes = append(modes, "-working-tree")
+    }
+    return modes
+}
 
-        // Handle {{ interpolation
-        if ch == '{' && i+1 < len(trimmed) && trimmed[i+1] == '{' {
-            currentLine.WriteString("{{")
-            i += 2
-            for i < len(trimmed) {
-                if trimmed[i] == '}' && i+1 < len(trimmed) && trimmed[i+1] == '}' {
-                    currentLine.WriteString("}}")
-                    i += 2
-                    break
-                }
-                currentLine.WriteByte(trimmed[i])
-                i++
+func gitChangedFiles(opts diffOptions) []string {
+    var rangeArgs []string
+    switch {
+    case opts.all:
+        logln("Listing all tracked files (git ls-files)")
+        var files []string
+        for _, f := range strings.Split(getCommandOutput("git", "ls-files", "--full-name"), "\n") {
+            if f != "" {
+                files = append(files, f)
             }
-            continue
         }
-
-        // Handle @directive
-        if ch == '@' && isControlFlowDirective(trimmed[i:]) {
-            flushWithDepth(&result, &currentLine, originalIndent, depth+localDepth)
-            directive, newPos := extractDirective(trimmed, i)
-            result = append(result, depthIndent(originalIndent, depth+localDepth)+directive)
-            i = newPos
-            for i < len(trimmed) && (trimmed[i] == ' ' || trimmed[i] == '\t') {
-                i++
-            }
-            if i < len(trimmed) && trimmed[i] == '{' {
-                result = append(result, depthIndent(originalIndent, depth+localDepth)+"{")
-                localDepth++
-                i++
-                for i < len(trimmed) && (trimmed[i] == ' ' || trimmed[i] == '\t') {
-                    i++
+        confirmAll(len(files))
+        return files
+
+    case opts.working:
+        logln("Calculating changes: working tree (unstaged, staged and untracked)")
+        var files []string
+        seen := make(map[string]bool)
+        diff := diffListing(opts.extra)
+        for _, args := range [][]string{
+            append(slices.Clone(diff), "--", "."),
+            append(slices.Clone(diff), "--cached", "--", "."),
+            {"ls-files", "--others", "--exclude-standard", "--full-name"},
+        } {
+            for _, line := range strings.Split(getCommandOutput("git", args...), "\n") {
+                f := renameTarget(diffLinePath(line))
+                if f != "" && !seen[f] {
+                    seen[f] = true
+                    files = append(files, f)
                 }
             }
-            continue
         }
+        return files
 
-        // Handle }
-        if ch == '}' {
-            flushWithDepth(&result, &currentLine, originalIndent, depth+localDepth)
-            localDepth--
-            if depth+localDepth < 0 {
-                localDepth = -depth
-            }
-            result = append(result, depthIndent(originalIndent, depth+localDepth)+"}")
-            i++
-            for i < len(trimmed) && (trimmed[i] == ' ' || trimmed[i] == '\t') {
-                i++
+    case opts.staged:
+        logln("Calculating changes: staged files (git index)")
+        rangeArgs = []string{"--cached"}
+
+    case opts.between != "":
+        from, to, ok := strings.Cut(opts.between, "..")
+        if !ok || from == "" || to == "" || strings.HasPrefix(to, ".") {
+            fatalf("-between expects two refs separated by '..' (e.g. v1.0.0..v1.1.0), got %q", opts.between)
+        }
+        for _, ref := range []string{from, to} {
+            if !isValidRef(ref) {
+                fatalf("Ref '%s' not found.", ref)
             }
-            continue
         }
+        logf("Calculating changes: %s..%s\n", from, to)
+        rangeArgs = []string{from, to}
 
-        // Handle standalone {
-        if ch == '{' {
-            flushWithDepth(&result, &currentLine, originalIndent, depth+localDepth)
-            result = append(result, depthIndent(originalIndent, depth+localDepth)+"{")
-            localDepth++
-            i++
-            for i < len(trimmed) && (trimmed[i] == ' ' || trimmed[i] == '\t') {
-                i++
+    case opts.since != "" || opts.until != "":
+        if opts.since == "" {
+            fatalf("-until requires -since.")
+        }
+        until := opts.until
+        if until == "" {
+            until = "HEAD"
+        }
+        for _, ref := range []string{opts.since, until} {
+            if !isValidRef(ref) {
+                fatalf("Ref '%s' not found.", ref)
             }
-            continue
         }
+        logf("Calculating changes: %s...%s\n", opts.since, until)
+        rangeArgs = []string{fmt.Sprintf("%s...%s", opts.since, until)}
+
+    default:
+        currentBranch, parentBranch := detectParentBranch(opts)
+        parentRef := resolveParentRef(parentBranch, opts.fetch)
+        logf("Calculating changes: %s...%s\n", parentBranch, currentBranch)
+        logf("Diff base: %s (%s)\n", parentRef, getCommandOutput("git", "rev-parse", "--short", parentRef))
+        rangeArgs = []string{fmt.Sprintf("%s...HEAD", parentRef)}
+    }
 
-        currentLine.WriteByte(ch)
-        i++
+    // Deleted files can't be formatted, so exclude them at the source. The
+    // "." pathspec is -path itself (git runs there), so a subfolder of a
+    // monorepo only diffs its own files; refs still resolve repo-wide.
+    diffArgs := diffListing(opts.extra)
+    diffArgs = append(diffArgs, rangeArgs...)
+    diffArgs = append(diffArgs, "--", ".")
+    output, err := runner.Output("git", diffArgs...)
+    if err != nil {
+        fatalf("Error running git diff: %v", err)
     }
 
-    flushWithDepth(&result, &currentLine, originalIndent, depth+localDepth)
+    var files []string
+    for _, line := range strings.Split(strings.TrimSpace(string(output)), "\n") {
+        files = append(files, renameTarget(diffLinePath(line)))
+    }
+    return files
+}
 
-    if len(result) == 0 {
-        result = []string{depthIndent(originalIndent, depth) + trimmed}
+// detectParentBranch returns the branch being formatted ("HEAD" when
+// detached) and the branch it is compared against: -base if given, else the
+// detected fork point.
+func detectParentBranch(opts diffOptions) (currentBranch, parentBranch string) {
+    currentBranch = getCommandOutput("git", "branch", "--show-current")
+    if currentBranch == "" {
+        // Detached HEAD (typical in CI): compare the checked-out commit itself
+        if !isValidRef("HEAD") {
+            fatalf("Could not detect current branch.")
+        }
+        currentBranch = "HEAD"
+        logln("Detached HEAD: comparing the checked-out commit (use -base to choose the parent).")
     }
 
-    return expandResult{
-        lines:      result,
-        finalDepth: depth + localDepth,
+    if opts.base != "" {
+        if !isValidRef(opts.base) {
+            fatalf("Base ref '%s' not found.", opts.base)
+        }
+        return currentBranch, opts.base
     }
+    parentBranch = findForkPoint(currentBranch)
+    if !isValidRef(parentBranch) {
+        warnf("Parent '%s' not found. Falling back to 'main'.\n", parentBranch)
+        parentBranch = "main"
+    }
+    return currentBranch, parentBranch
 }
 
-func depthIndent(originalIndent string, depth int) string {
-    if depth < 0 {
-        depth = 0
+// diffListing starts a git diff that lists changed files. With -diff-args it
+// uses --numstat, because --name-only still lists a file whose only changes
+// the whitespace options ignore, while --numstat leaves it out.
+func diffListing(extra []string) []string {
+    listing := "--name-only"
+    if len(extra) > 0 {
+        listing = "--numstat"
     }
-    return strings.Repeat(indentUnit, depth) + originalIndent
+    return append([]string{"diff", listing, "--diff-filter=d"}, extra...)
 }
 
-func flushWithDepth(result *[]string, currentLine *strings.Builder, originalIndent string, depth int) {
-    content := strings.TrimSpace(currentLine.String())
-    if content != "" {
-        *result = append(*result, depthIndent(originalIndent, depth)+content)
+// diffLinePath returns the path of a --name-only or --numstat line
+// ("12\t3\tsrc/a.ts", "-\t-\timg.png" for binary files). --name-status lines
+// work too; renames and copies ("R100\told\tnew", "C75\told\tnew") give
+// the new path. Paths git quoted for unusual characters are unquoted.
+func diffLinePath(line string) string {
+    parts := strings.Split(line, "\t")
+    switch {
+    case len(parts) == 3 && isDiffStatus(parts[0], "RC"):
+        return unquoteGitPath(parts[2])
+    case len(parts) == 2 && isDiffStatus(parts[0], "AMDTU"):
+        return unquoteGitPath(parts[1])
+    case len(parts) >= 3:
+        return unquoteGitPath(strings.Join(parts[2:], "\t"))
     }
-    currentLine.Reset()
+    return unquoteGitPath(line)
 }
 
-func isControlFlowDirective(s string) bool {
-    directives := []string{"@if", "@else if", "@else", "@switch", "@case", "@default", "@for", "@empty"}
-    for _, d := range directives {
-        if strings.HasPrefix(s, d) {
-            if len(s) == len(d) {
-                return true
-            }
-            next := s[len(d)]
-            if next == ' ' || next == '(' || next == '{' || next == '\n' || next == '\t' {
-                return true
-            }
-        }
+// isDiffStatus reports whether field is a --name-status letter from letters,
+// optionally followed by a similarity score ("R100").
+func isDiffStatus(field, letters string) bool {
+    if field == "" || !strings.ContainsRune(letters, rune(field[0])) {
+        return false
     }
-    return false
+    _, err := strconv.Atoi(field[1:])
+    return len(field) == 1 || err == nil
 }
 
-func extractDirective(line string, start int) (string, int) {
-    i := start
-    parenDepth := 0
-    inParens := false
-
-    for i < len(line) {
-        ch := line[i]
-        if ch == '(' {
-            parenDepth++
-            inParens = true
-        } else if ch == ')' {
-            parenDepth--
-            if parenDepth == 0 && inParens {
-                return line[start : i+1], i + 1
-            }
-        } else if ch == '{' && parenDepth == 0 {
-            return strings.TrimSpace(line[start:i]), i
-        }
-        i++
+// unquoteGitPath undoes git's C-style quoting of paths with special
+// characters ("src/caf\303\251.html" with core.quotePath). Other paths, and
+// quoted ones that don't parse, are returned as they are.
+func unquoteGitPath(p string) string {
+    if len(p) < 2 || p[0] != '"' || p[len(p)-1] != '"' {
+        return p
+    }
+    if unquoted, err := strconv.Unquote(p); err == nil {
+        return unquoted
     }
-    return strings.TrimSpace(line[start:]), len(line)
+    return p
 }
 
-func extractIndent(line string) string {
-    for i, ch := range line {
-        if ch != ' ' && ch != '\t' {
-            return line[:i]
-        }
+// confirmAll asks before -all rewrites a whole repository. Dry runs, -list
+// and -yes skip the question; without a terminal to ask on, -yes is required.
+func confirmAll(count int) {
+    if dryRun || assumeYes || listOnly {
+        return
+    }
+    warnf("Warning: -all will format %d tracked file(s) and may produce a large diff.\n", count)
+    if !stdinIsTerminal() {
+        fatalf("-all needs confirmation; pass -yes to run non-interactively.")
+    }
+    if !confirm("Continue?") {
+        warnf("Aborted.\n")
+        os.Exit(0)
     }
-    return ""
 }
-// --- UTILITIES ---
 
-func findForkPoint(currentBranch string) string {
-    reflogOut := getCommandOutput("git", "reflog", "--date=iso")
-    lines := strings.Split(reflogOut, "\n")
-    for _, line := range lines {
-        if strings.Contains(line, "moving from ") && strings.Contains(line, fmt.Sprintf(" to %s", currentBranch)) {
-            parts := strings.Split(line, "moving from ")
-            if len(parts) > 1 {
-                remainder := parts[1]
-                toParts := strings.Split(remainder, " to ")
-                candidate := strings.TrimSpace(toParts[0])
-                if isSameBranch(candidate, currentBranch) {
-                    continue
-                }
-                return candidate
-            }
-        }
+func stdinIsTerminal() bool {
+    info, err := os.Stdin.Stat()
+    return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// confirm asks a yes/no question on stderr and reads the answer from stdin.
+// Anything but "y" or "yes" counts as no.
+func confirm(question string) bool {
+    warnf("%s [y/N] ", question)
+    answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+    answer = strings.ToLower(strings.TrimSpace(answer))
+    return answer == "y" || answer == "yes"
+}
+
+// renameTarget extracts the new path from rename notation such as
+// "old.html => new.html" or "src/{old => new}/file.ts". Other lines are
+// returned unchanged.
+func renameTarget(line string) string {
+    line = strings.TrimSpace(line)
+    if !strings.Contains(line, " => ") {
+        return line
     }
-    candidates := []string{"main", "master", "develop", "origin/main", "origin/master"}
-    for _, c := range candidates {
-        if isValidRef(c) {
-            if isSameBranch(c, currentBranch) {
-                continue
-            }
-            return c
+
+    lb := strings.Index(line, "{")
+    rb := strings.LastIndex(line, "}")
+    if lb >= 0 && rb > lb {
+        inner := line[lb+1 : rb]
+        if _, newPart, ok := strings.Cut(inner, " => "); ok {
+            joined := line[:lb] + newPart + line[rb+1:]
+            // "{ => sub}" style renames can leave doubled slashes behind
+            return strings.ReplaceAll(joined, "//", "/")
         }
     }
-    return "main"
+
+    _, newPath, _ := strings.Cut(line, " => ")
+    return unquoteGitPath(strings.TrimSpace(newPath))
 }
 
-func isSameBranch(candidate, current string) bool {
-    if candidate == current || candidate == "origin/"+current {
-        return true
+// resolveRepoPath makes a user-supplied path absolute, relative to repoPath.
+func resolveRepoPath(p string) string {
+    if filepath.IsAbs(p) {
+        return p
     }
-    if strings.HasSuffix(candidate, "/"+current) {
-        return true
+    return filepath.Join(repoPath, p)
+}
+
+// readFileList reads one path per line, as produced by `git diff --name-only`
+// or lint-staged. Blank lines are skipped and relative paths resolve against
+// repoPath. Missing files are left to processChanges, which skips them.
+func readFileList(r io.Reader) []string {
+    var files []string
+    scanner := bufio.NewScanner(r)
+    for scanner.Scan() {
+        line := strings.TrimSpace(scanner.Text())
+        if line == "" {
+            continue
+        }
+        files = append(files, resolveRepoPath(line))
     }
-    return false
+    if err := scanner.Err(); err != nil {
+        fatalf("Error reading file list from stdin: %v", err)
+    }
+    return files
 }
 
-func isValidRef(ref string) bool {
-    cmd := exec.Command("git", "rev-parse", "--verify", ref)
-    cmd.Dir = repoPath
-    return cmd.Run() == nil
+// stringList is a repeatable string flag.
+type stringList []string
+
+func (s *stringList) String() string {
+    return strings.Join(*s, ",")
 }
 
-func getCommandOutput(name string, args ...string) string {
-    cmd := exec.Command(name, args...)
-    cmd.Dir = repoPath
-    out, err := cmd.CombinedOutput()
+func (s *stringList) Set(value string) error {
+    *s = append(*s, value)
+    return nil
+}
+
+// --- TOOL ENVIRONMENT SETUP ---
+
+// resolveToolHome sets toolHome to an absolute path without creating anything.
+func resolveToolHome() {
+    // -tool-home wins over INSIPP_TOOL_HOME, which wins over ~/.insipp-linter-tool
+    if toolHome == "" {
+        toolHome = os.Getenv(toolHomeEnv)
+    }
+    if toolHome == "" {
+        toolHomeIsDefault = true
+        homeDir, err := os.UserHomeDir()
+        if err != nil {
+            fatalf("Could not find user home directory: %v", err)
+        }
+        toolHome = filepath.Join(homeDir, ".insipp-linter-tool")
+    }
+    absHome, err := filepath.Abs(toolHome)
     if err != nil {
-        return ""
+        fatalf("Error resolving tool home %s: %v", toolHome, err)
     }
-    return strings.TrimSpace(string(out))
+    toolHome = absHome
+}
+
+func setupToolEnvironment() {
+    resolveToolHome()
+
+    if preferLocal {
+        _, hasEslint := resolveBin(repoPath, "eslint")
+        _, hasPrettier := resolveBin(repoPath, "prettier")
+        if hasEslint && hasPrettier {
+            useLocalTools = true
+            logf("Using the project's ESLint and Prettier from %s.\n", filepath.Join(repoPath, "node_modules"))
+        } else {
+            logln("-prefer-local: the project does not have both ESLint and Prettier installed; using the embedded toolchain.")
+        }
+    }
+
+    if readOnly || noInstall {
+        if !useLocalTools {
+            mode := "-read-only"
+            if noInstall {
+                mode = "-no-install"
+            }
+            verifyToolEnvironment(mode)
+            checkToolVersions()
+        }
+        return
+    }
+    if err := prepareToolHome(toolHome); err != nil {
+        switch {
+        case useLocalTools || toolEnvironmentError("-no-install") == nil:
+            // Provisioned by someone else, e.g. baked into a read-only image
+            warnf("%s\n", yellow(fmt.Sprintf("Warning: tool directory %s is not writable (%v); using it as it is, without config sync, install or cache.", toolHome, err)))
+            noInstall = true
+            if !useLocalTools {
+                checkToolVersions()
+            }
+            return
+        case toolHomeIsDefault:
+            fallback := fallbackToolHome()
+            warnf("%s\n", yellow(fmt.Sprintf("Warning: tool directory %s is not writable (%v); using %s instead. Pass -tool-home or set $%s to choose a permanent location.", toolHome, err, fallback, toolHomeEnv)))
+            toolHome = fallback
+            if err := prepareToolHome(toolHome); err != nil {
+                fatalf("Fallback tool directory %s is not writable either: %v. Pass -tool-home (or set $%s) with a writable directory.", toolHome, err, toolHomeEnv)
+            }
+        default:
+            fatalf("Tool directory %s is not writable: %v. Pass -tool-home (or set $%s) with a writable directory, or use -no-install if it is already provisioned.", toolHome, err, toolHomeEnv)
+        }
+    }
+    // toolHome still holds the cache, but nothing needs to be installed
+    if useLocalTools {
+        return
+    }
+
+    // Runs sharing toolHome sync configs and install one at a time
+    lock, err := lockToolHome(toolHome, installTimeout)
+    if err != nil {
+        fatalf("Could not lock tool directory: %v", err)
+    }
+    installLock = lock
+    defer lock.release()
+
+    // Helper to extract embedded files to the user's disk
+    extractFile := func(embedPath, destName string) {
+        if err := syncConfig(embedPath, destName); err != nil {
+            fatalf("Config %s in %s is corrupt and could not be regenerated: %v. Delete the folder and run again.", destName, toolHome, err)
+        }
+    }
+
+    // Keep configs identical to the ones embedded in this binary
+    extractFile("configs/eslint.config.mjs", "eslint.config.mjs")
+    extractFile("configs/.prettierrc", ".prettierrc")
+
+    // Offline runs never reach the registry, so the tool home must be pre-warmed
+    if offline {
+        for _, name := range []string{"eslint", "prettier"} {
+            if _, ok := resolveBin(toolHome, name); !ok {
+                fatalf("Offline mode: %s is not installed in %s. Populate its node_modules (e.g. from a cached layer) or run without -offline.", name, toolHome)
+            }
+        }
+        checkToolVersions()
+        return
+    }
+
+    // Check if we need to install/update dependencies. A package.json that
+    // differs from the embedded one means this binary added or bumped a dependency.
+    pkgDest := filepath.Join(toolHome, "package.json")
+    onDisk, pkgErr := os.ReadFile(pkgDest)
+    embeddedPkg, _ := configFiles.ReadFile("configs/package.json")
+    _, binFound := resolveBin(toolHome, "prettier")
+
+    needsInstall := pkgErr != nil || !bytes.Equal(onDisk, embeddedPkg) || !binFound
+    // Likewise for a lockfile that pins different versions
+    embeddedLockFile, hasLock := embeddedLock()
+    if hasLock {
+        lockOnDisk, err := os.ReadFile(filepath.Join(toolHome, embeddedLockName))
+        needsInstall = needsInstall || err != nil || !bytes.Equal(lockOnDisk, embeddedLockFile)
+    }
+
+    if needsInstall {
+        logf("Updating linter environment (installing Prettier/ESLint with %s)...\n", packageManager)
+
+        // Write package.json only when installing to trigger updates if needed
+        extractFile("configs/package.json", "package.json")
+        if hasLock {
+            extractFile("configs/"+embeddedLockName, embeddedLockName)
+        }
+
+        assertWritable(packageManager + " install")
+        for attempt := 1; ; attempt++ {
+            cmd := newTimedCmd(installTimeout, packageManagerExecutable(packageManager), installArgs()...)
+            cmd.Dir = toolHome
+            cmd.Env = installEnv()
+            // Keep a copy of the output to tell registry hiccups from real failures
+            var captured bytes.Buffer
+            cmd.Stdout = io.MultiWriter(logOut, &captured)
+            cmd.Stderr = io.MultiWriter(os.Stderr, &captured)
+
+            logCommand(cmd)
+            err := runWithHeartbeat(cmd)
+            if err == nil {
+                break
+            }
+            if attempt >= installRetries || !isTransientInstallError(captured.String()) {
+                fatalf("Failed to install linter dependencies: %v", err)
+            }
+            delay := installBackoff << (attempt - 1)
+            warnf("Install attempt %d/%d failed with a network error; retrying in %s...\n", attempt, installRetries, delay)
+            time.Sleep(delay)
+        }
+        logln("Tool environment ready.")
+    }
+    checkToolVersions()
+}
+
+// installRetries is the total number of install attempts (-install-retries).
+var installRetries int
+
+// installBackoff is the wait after the first failed attempt; it doubles each time.
+const installBackoff = 2 * time.Second
+
+// transientInstallMarkers appear in npm/pnpm/yarn output for failures worth
+// retrying. Anything else (a bad package.json, a missing version) fails fast.
+var transientInstallMarkers = []string{
+    "ETIMEDOUT", "ECONNRESET", "ECONNREFUSED", "ENOTFOUND", "EAI_AGAIN", "ENETUNREACH",
+    "socket hang up", "network", "502 Bad Gateway", "503 Service Unavailable", "429 Too Many Requests",
+}
+
+func isTransientInstallError(output string) bool {
+    for _, marker := range transientInstallMarkers {
+        if strings.Contains(output, marker) {
+            return true
+        }
+    }
+    return false
+}
+
+// installHeartbeat is how long an install may stay silent before a
+// "still installing" line is printed.
+const installHeartbeat = 5 * time.Second
+
+// activityWriter forwards output and remembers when it last saw any.
+type activityWriter struct {
+    w    io.Writer
+    last *atomic.Int64 // unix nanoseconds
+}
+
+func (a activityWriter) Write(p []byte) (int, error) {
+    a.last.Store(time.Now().UnixNano())
+    return a.w.Write(p)
+}
+
+// runWithHeartbeat runs cmd and prints the elapsed time whenever it has been
+// silent for installHeartbeat, so a slow install doesn't look like a hang.
+// Commands that keep producing output never trigger it.
+func runWithHeartbeat(cmd *timedCmd) error {
+    start := time.Now()
+    var last atomic.Int64
+    last.Store(start.UnixNano())
+    cmd.Stdout = activityWriter{w: cmd.Stdout, last: &last}
+    cmd.Stderr = activityWriter{w: cmd.Stderr, last: &last}
+
+    if err := cmd.Start(); err != nil {
+        return err
+    }
+    done := make(chan error, 1)
+    go func() { done <- cmd.Wait() }()
+
+    ticker := time.NewTicker(time.Second)
+    defer ticker.Stop()
+    for {
+        select {
+        case err := <-done:
+            return err
+        case <-ticker.C:
+            if time.Since(time.Unix(0, last.Load())) >= installHeartbeat {
+                logf("Still installing... (%s elapsed)\n", time.Since(start).Round(time.Second))
+                last.Store(time.Now().UnixNano())
+            }
+        }
+    }
+}
+
+// syncConfig makes toolHome/destName identical to the embedded file. It only
+// writes when the checksums differ, goes through a temp file so a killed run
+// can't leave a truncated config behind, and verifies what ended up on disk.
+func syncConfig(embedPath, destName string) error {
+    content, err := configFiles.ReadFile(embedPath)
+    if err != nil {
+        return fmt.Errorf("reading embedded %s: %w", embedPath, err)
+    }
+    destPath := filepath.Join(toolHome, destName)
+    want := sha256.Sum256(content)
+    if onDisk, err := os.ReadFile(destPath); err == nil && sha256.Sum256(onDisk) == want {
+        return nil
+    }
+
+    verbosef("Rewriting %s (missing or checksum mismatch).", destPath)
+    tmp := destPath + ".tmp"
+    if err := writeFile(tmp, content, 0644); err != nil {
+        return err
+    }
+    if err := os.Rename(tmp, destPath); err != nil {
+        os.Remove(tmp)
+        return err
+    }
+
+    written, err := os.ReadFile(destPath)
+    if err != nil {
+        return err
+    }
+    if sha256.Sum256(written) != want {
+        return errors.New("checksum still differs after rewriting")
+    }
+    return validateConfig(destName, written)
+}
+
+// validateConfig catches configs that would make ESLint/Prettier fall back to
+// defaults silently: empty files, and JSON that doesn't parse.
+func validateConfig(name string, content []byte) error {
+    if len(bytes.TrimSpace(content)) == 0 {
+        return errors.New("file is empty")
+    }
+    if name == ".prettierrc" || strings.HasSuffix(name, ".json") {
+        if !json.Valid(content) {
+            return errors.New("file is not valid JSON")
+        }
+    }
+    return nil
+}
+
+// prettierPluginPath returns the entry file of a Prettier plugin installed in
+// toolHome, or false if it is not installed.
+func prettierPluginPath(name string) (string, bool) {
+    dir := filepath.Join(toolHome, "node_modules", name)
+    content, err := os.ReadFile(filepath.Join(dir, "package.json"))
+    if err != nil {
+        return "", false
+    }
+    var pkg struct {
+        Main string `json:"main"`
+    }
+    json.Unmarshal(content, &pkg)
+    if pkg.Main == "" {
+        pkg.Main = "index.js"
+    }
+    return filepath.Join(dir, pkg.Main), true
+}
+
+// prepareToolHome creates dir if needed and checks that files can be written there.
+func prepareToolHome(dir string) error {
+    if err := os.MkdirAll(dir, 0755); err != nil {
+        return err
+    }
+    return checkWritable(dir)
+}
+
+// fallbackToolHome is used when the default tool home is not writable, e.g.
+// on a read-only home mount. It is per user where the OS has user IDs, so a
+// shared temp directory doesn't mix two users' installs.
+func fallbackToolHome() string {
+    name := "insipp-linter-tool"
+    if uid := os.Getuid(); uid >= 0 {
+        name = fmt.Sprintf("%s-%d", name, uid)
+    }
+    return filepath.Join(os.TempDir(), name)
+}
+
+// checkWritable verifies dir accepts new files by creating and removing a probe.
+func checkWritable(dir string) error {
+    probe, err := os.CreateTemp(dir, ".write-probe-*")
+    if err != nil {
+        return err
+    }
+    probe.Close()
+    return os.Remove(probe.Name())
+}
+
+// packageManagers lists the supported installers in auto-detection order.
+var packageManagers = []string{"npm", "pnpm", "yarn"}
+
+// detectPackageManager picks the first supported package manager found on PATH.
+func detectPackageManager() string {
+    for _, pm := range packageManagers {
+        if _, err := exec.LookPath(packageManagerExecutable(pm)); err == nil {
+            return pm
+        }
+    }
+    return "npm"
+}
+
+// installArgs is the install command line for packageManager: "npm ci" with
+// an embedded lockfile, "install" otherwise. npm and pnpm take the registry
+// as a flag; Yarn gets it from installEnv, since Yarn 2+ has no --registry.
+func installArgs() []string {
+    args := []string{"install"}
+    // A lockfile makes npm install exactly what it lists, and nothing else
+    if _, hasLock := embeddedLock(); hasLock && packageManager == "npm" {
+        args = []string{"ci"}
+    }
+    if npmRegistry != "" && packageManager != "yarn" {
+        args = append(args, "--registry", npmRegistry)
+    }
+    return args
+}
+
+// installEnv is the environment for the install command.
+func installEnv() []string {
+    // Yarn 2+ defaults to Plug'n'Play; the tool needs a real node_modules/.bin
+    env := append(os.Environ(), "YARN_NODE_LINKER=node-modules")
+    if npmRegistry != "" && packageManager == "yarn" {
+        // Yarn 1 reads the first, Yarn 2+ the second
+        env = append(env, "YARN_REGISTRY="+npmRegistry, "YARN_NPM_REGISTRY_SERVER="+npmRegistry)
+    }
+    if npmrcPath != "" {
+        // npm, pnpm and Yarn 1 all read the user config from here
+        env = append(env, "NPM_CONFIG_USERCONFIG="+npmrcPath)
+    }
+    return env
+}
+
+func packageManagerExecutable(pm string) string {
+    if runtime.GOOS == "windows" {
+        return pm + ".cmd"
+    }
+    return pm
+}
+
+// verifyToolEnvironment checks that a previous run already provisioned toolHome.
+// Read-only and -no-install runs cannot extract configs or install
+// dependencies themselves; mode names that flag in the error messages.
+func verifyToolEnvironment(mode string) {
+    if err := toolEnvironmentError(mode); err != nil {
+        fatalf("%v", err)
+    }
+}
+
+// toolEnvironmentError describes the first thing missing from toolHome, or
+// returns nil if it is fully provisioned.
+func toolEnvironmentError(mode string) error {
+    for _, name := range []string{"eslint.config.mjs", ".prettierrc"} {
+        path := filepath.Join(toolHome, name)
+        onDisk, err := os.ReadFile(path)
+        if err != nil {
+            return fmt.Errorf("%s: %s is missing. Run once without %s to provision it.", mode, path, mode)
+        }
+        embedded, _ := configFiles.ReadFile("configs/" + name)
+        if sha256.Sum256(onDisk) != sha256.Sum256(embedded) {
+            return fmt.Errorf("%s: %s differs from this binary's config (truncated or outdated). Run once without %s to regenerate it.", mode, path, mode)
+        }
+    }
+    for _, name := range []string{"eslint", "prettier"} {
+        if _, ok := resolveBin(toolHome, name); !ok {
+            return fmt.Errorf("%s: %s is not installed (expected %s). Run once without %s to provision it.", mode, name, filepath.Join(toolHome, "node_modules", ".bin", name), mode)
+        }
+    }
+    return nil
+}
+
+// assertWritable is called on every path that mutates disk. Reaching it in
+// read-only mode is a programming error, so it panics rather than writing.
+func assertWritable(action string) {
+    if readOnly {
+        panic(fmt.Sprintf("read-only violation: attempted to %s", action))
+    }
+}
+
+// writeFile is the single choke point for writing files to disk. The only
+// writes that bypass it are made on purpose in -read-only runs and never
+// touch the repository: the -junit and -sarif reports the user asked for by
+// path, and the scratch copy -difftool opens in a temp directory.
+func writeFile(path string, data []byte, perm os.FileMode) error {
+    haltIfInterrupted()
+    assertWritable("write " + path)
+    return os.WriteFile(path, data, perm)
+}
+
+// resolveBin locates the executable shim for a Node tool under root/node_modules/.bin.
+// npm writes .cmd shims on Windows, but pnpm/yarn may only provide .ps1 or
+// extensionless scripts, so each variant is probed in order of preference.
+// The boolean reports whether any candidate actually exists.
+func resolveBin(root, name string) (string, bool) {
+    base := filepath.Join(root, "node_modules", ".bin", name)
+    candidates := []string{base}
+    if runtime.GOOS == "windows" {
+        candidates = []string{base + ".cmd", base + ".exe", base + ".ps1", base}
+    }
+
+    for _, c := range candidates {
+        if info, err := os.Stat(c); err == nil && !info.IsDir() {
+            return c, true
+        }
+    }
+    return candidates[0], false
+}
+
+// binCommand builds the command for a resolved shim. PowerShell shims cannot be
+// executed directly, so they are run through powershell -File.
+func binCommand(bin string, args ...string) *timedCmd {
+    if strings.EqualFold(filepath.Ext(bin), ".ps1") {
+        psArgs := []string{"-NoProfile", "-ExecutionPolicy", "Bypass", "-File", bin}
+        return newTimedCmd(toolTimeout, "powershell", append(psArgs, args...)...)
+    }
+    return newTimedCmd(toolTimeout, bin, args...)
+}
+
+// --- FILE PROCESSING ---
+
+// processChanges routes files to the right tool. Paths may be absolute or
+// relative to repoPath; duplicates (by absolute path) are processed once.
+func processChanges(files []string) {
+    buckets := make(map[string][]string) // handler name -> files
+    seen := make(map[string]bool)
+    ignore := loadIgnoreFile(filepath.Join(repoPath, ignoreFileName))
+    skip := parseIgnore(strings.Join(skipGlobs, "\n"))
+    only := parseIgnore(strings.Join(onlyGlobs, "\n"))
+    onlyMatched, onlyTotal := 0, 0
+    cache := loadCache()
+    var routed []string
+
+    // Count why files were dropped so "No ... files" messages are explainable
+    var skipReasons []string
+    skipCounts := make(map[string]int)
+    skipFile := func(reason string) {
+        if skipCounts[reason] == 0 {
+            skipReasons = append(skipReasons, reason)
+        }
+        skipCounts[reason]++
+    }
+
+    for _, f := range files {
+        f = strings.TrimSpace(f)
+        if f == "" {
+            continue
+        }
+        // git always prints forward slashes; work with native paths from here
+        // on and match globs against the slash form (relPath)
+        fullPath := filepath.FromSlash(f)
+        if !filepath.IsAbs(fullPath) {
+            fullPath = filepath.Join(repoPath, fullPath)
+        }
+        fullPath = filepath.Clean(fullPath)
+        if seen[fullPath] {
+            continue
+        }
+        seen[fullPath] = true
+
+        if _, err := os.Stat(fullPath); os.IsNotExist(err) {
+            skipFile("deleted")
+            continue
+        }
+
+        if len(onlyGlobs) > 0 {
+            onlyTotal++
+            if !only.Match(relPath(fullPath)) {
+                skipFile("not matched by -only")
+                continue
+            }
+            onlyMatched++
+        }
+        if isGenerated(relPath(fullPath)) {
+            skipFile("generated (see -include-generated)")
+            continue
+        }
+        if ignore.Match(relPath(fullPath)) {
+            skipFile("matched by " + ignoreFileName)
+            continue
+        }
+        if skip.Match(skipGlobPath(fullPath)) {
+            skipFile("matched by -skip-glob")
+            continue
+        }
+
+        if excludedExts[extOf(f)] {
+            skipFile("excluded by -exclude-ext")
+            continue
+        }
+        tool := toolFor(extOf(f))
+        if tool == "" {
+            skipFile("unsupported extension")
+            continue
+        }
+
+        // Unchanged since the last successful format: nothing to do
+        if cache.fresh(fullPath) {
+            skipFile("unchanged since last format (see -no-cache)")
+            continue
+        }
+        buckets[tool] = append(buckets[tool], fullPath)
+        routed = append(routed, fullPath)
+    }
+
+    if len(onlyGlobs) > 0 {
+        logf("-only: %d of %d file(s) matched.\n", onlyMatched, onlyTotal)
+    }
+    if len(skipReasons) > 0 {
+        total := 0
+        var parts []string
+        for _, reason := range skipReasons {
+            total += skipCounts[reason]
+            parts = append(parts, fmt.Sprintf("%d %s", skipCounts[reason], reason))
+        }
+        logf("Skipped %d file(s): %s.\n", total, strings.Join(parts, ", "))
+    }
+
+    if listOnly {
+        var lists []fileList
+        for _, h := range toolHandlers {
+            if h.name != "native" {
+                lists = append(lists, fileList{h.label, buckets[h.name]})
+            }
+        }
+        var names []string
+        byFormatter := make(map[string][]string)
+        for _, f := range buckets["native"] {
+            name := formatters[extOf(f)].name
+            if byFormatter[name] == nil {
+                names = append(names, name)
+            }
+            byFormatter[name] = append(byFormatter[name], f)
+        }
+        for _, name := range names {
+            lists = append(lists, fileList{"Built-in (" + name + ")", byFormatter[name]})
+        }
+        printFileLists(lists)
+        return
+    }
+
+    // Hash up front so the summary can tell which files the tools really rewrote
+    before := make(map[string]string)
+    for _, f := range routed {
+        before[f], _ = hashFile(f)
+    }
+
+    for _, h := range toolHandlers {
+        bucket := buckets[h.name]
+        if len(bucket) > 0 {
+            timePhase(h.label, len(bucket), func() { h.run(bucket) })
+        } else if h.idle != "" {
+            logln(h.idle)
+        }
+        switch h.name {
+        case "eslint":
+            summary.linted += len(bucket)
+        case "html":
+            summary.html += len(bucket)
+        default:
+            summary.other += len(bucket)
+        }
+    }
+    wouldChange := changedPaths()
+    var rewritten []string
+    for _, f := range routed {
+        after, err := hashFile(f)
+        if err != nil {
+            // Deleted during the run; reported as skipped by the tools
+            continue
+        }
+        if after != before[f] {
+            rewritten = append(rewritten, f)
+        }
+        if after != before[f] || wouldChange[f] {
+            summary.changed++
+        }
+    }
+    if failOnChange && len(rewritten) > 0 {
+        warnf("\n%d file(s) were reformatted (-fail-on-change); commit the result:\n", len(rewritten))
+        for _, f := range rewritten {
+            warnf("  %s\n", relPath(f))
+        }
+        setExitStatus(1)
+    }
+
+    // Remember files that came through cleanly; dry runs leave the cache alone
+    if !dryRun {
+        cache.record(routed)
+        cache.save()
+    }
+}
+
+// fileList is one group of -list: the files a tool would handle.
+type fileList struct {
+    tool  string
+    files []string
+}
+
+// printFileLists prints the -list groups that have files, as text or, with
+// -format json, as a single JSON object like the report. Unlike progress
+// messages it goes to stdout even with -quiet, since it is the output being
+// asked for.
+func printFileLists(lists []fileList) {
+    if outputFormat == "json" {
+        type jsonFileList struct {
+            Tool  string   `json:"tool"`
+            Files []string `json:"files"`
+        }
+        report := struct {
+            Lists []jsonFileList `json:"lists"`
+        }{Lists: []jsonFileList{}}
+        for _, l := range lists {
+            if len(l.files) == 0 {
+                continue
+            }
+            entry := jsonFileList{Tool: l.tool}
+            for _, f := range l.files {
+                entry.Files = append(entry.Files, relPath(f))
+            }
+            report.Lists = append(report.Lists, entry)
+        }
+        content, err := json.MarshalIndent(report, "", "  ")
+        if err != nil {
+            warnf("Error encoding JSON list: %v\n", err)
+            return
+        }
+        fmt.Println(string(content))
+        return
+    }
+    for _, l := range lists {
+        if len(l.files) == 0 {
+            continue
+        }
+        fmt.Printf("%s (%d):\n", l.tool, len(l.files))
+        for _, f := range l.files {
+            fmt.Printf("  %s\n", relPath(f))
+        }
+    }
+}
+
+// toolHandler is one processing pipeline that files can be routed to.
+type toolHandler struct {
+    name  string // key used in extensionTools, -map-ext and the config file
+    label string // shown by -list and in the summary
+    idle  string // printed when no file was routed here ("" prints nothing)
+    run   func(files []string)
+}
+
+// toolHandlers run in this order.
+var toolHandlers = []toolHandler{
+    {name: "eslint", label: "ESLint", idle: "No JS/TS files to lint.", run: func(files []string) {
+        runEslint(files)
+        runImportSort(files)
+        runInlineTemplates(files)
+    }},
+    {name: "html", label: "HTML", idle: "No HTML files to process.", run: runHtmlProcessing},
+    {name: "style", label: "Stylesheet", run: func(files []string) { runPrettierOnly("Stylesheet", files) }},
+    {name: "data", label: "JSON/YAML", run: func(files []string) { runPrettierOnly("JSON/YAML", files) }},
+    {name: "markup", label: "Vue/Svelte", run: func(files []string) { runPrettierOnly("Vue/Svelte", files) }},
+    {name: "prettier", label: "Prettier", run: func(files []string) { runPrettierOnly("Other", files) }},
+    {name: "markdown", label: "Markdown", run: runMarkdown},
+    {name: "native", label: "Built-in", run: runNativeFormatters},
+}
+
+// routeExtension sends ext to the named handler, overriding the built-in table.
+// "native" is excluded since it only works for extensions with a registered formatter.
+func routeExtension(ext, tool string) error {
+    ext = normalizeExt(ext)
+    var names []string
+    for _, h := range toolHandlers {
+        if h.name == "native" {
+            continue
+        }
+        if h.name == tool {
+            extensionRoutes[ext] = tool
+            return nil
+        }
+        names = append(names, h.name)
+    }
+    return fmt.Errorf("extension %s: unknown tool %q (expected one of %s)", ext, tool, strings.Join(names, ", "))
+}
+
+// excludedExts are extensions set with -exclude-ext: files are still
+// collected (and reported as skipped) but no handler runs on them.
+var excludedExts = map[string]bool{}
+
+// normalizeExt turns "HTML", ".Html" or " .html" into ".html".
+func normalizeExt(ext string) string {
+    ext = strings.ToLower(strings.TrimSpace(ext))
+    if !strings.HasPrefix(ext, ".") {
+        ext = "." + ext
+    }
+    return ext
+}
+
+// extensionTools routes each supported extension to its processing pipeline.
+// Supporting a new file type is one entry here. Declaration files (.d.ts)
+// have the extension ".ts" and so go through ESLint like any other .ts file.
+var extensionTools = map[string]string{
+    ".js": "eslint", ".jsx": "eslint", ".ts": "eslint", ".tsx": "eslint", ".mjs": "eslint", ".cjs": "eslint",
+    ".html": "html",
+    ".css": "style", ".scss": "style", ".less": "style",
+    ".json": "data", ".yaml": "data", ".yml": "data",
+    ".vue": "markup", ".svelte": "markup",
+    ".md": "markdown", ".markdown": "markdown",
+}
+
+// toolFor names the processing pipeline for an extension, or "" if unsupported.
+func toolFor(ext string) string {
+    if tool, ok := extensionRoutes[ext]; ok {
+        return tool
+    }
+    if tool, ok := extensionTools[ext]; ok {
+        return tool
+    }
+    if _, ok := formatters[ext]; ok {
+        return "native"
+    }
+    return ""
+}
+
+func extOf(path string) string {
+    return strings.ToLower(filepath.Ext(path))
+}
+
+// toolRoot is the directory whose node_modules/.bin provides ESLint and Prettier.
+func toolRoot() string {
+    if useLocalTools {
+        return repoPath
+    }
+    return toolHome
+}
+
+// configArgs returns the --config flag for a tool: the -eslint-config /
+// -prettier-config override, else the embedded config in toolHome. With the
+// project's own toolchain it is empty so the tool finds the project's config.
+func configArgs(override, embeddedName string) []string {
+    switch {
+    case override != "":
+        return []string{"--config", resolveRepoPath(override)}
+    case useLocalTools:
+        return nil
+    default:
+        return []string{"--config", filepath.Join(toolHome, embeddedName)}
+    }
+}
+
+func runEslint(files []string) {
+    eslintBin, _ := resolveBin(toolRoot(), "eslint")
+
+    configFlags := configArgs(eslintConfig, "eslint.config.mjs")
+    args := slices.Clone(configFlags)
+    if dryRun {
+        logf("Running ESLint (dry run) on %d file(s)...\n", len(files))
+    } else {
+        logf("Running ESLint --fix on %d file(s)...\n", len(files))
+        assertWritable("run eslint --fix")
+        args = append(args, "--fix")
+    }
+
+    if maxWarnings >= 0 {
+        args = append(args, "--max-warnings", strconv.Itoa(maxWarnings))
+    }
+
+    var mu sync.Mutex
+    remaining, warnings := 0, 0
+    var runErr error
+    runChunks(files, func(chunk []string, out io.Writer) {
+        n, w, err := lintChunk(eslintBin, configFlags, args, chunk, out)
+        mu.Lock()
+        defer mu.Unlock()
+        remaining += n
+        warnings += w
+        if err != nil {
+            runErr = err
+        }
+    })
+
+    tooManyWarnings := maxWarnings >= 0 && warnings > maxWarnings
+    if tooManyWarnings {
+        setExitStatus(1)
+    }
+    switch {
+    case runErr != nil:
+        warnf("\n%s\n", red(fmt.Sprintf("ESLint failed to run: %v", runErr)))
+    case remaining > 0:
+        warnf("\n%s\n", red(fmt.Sprintf("ESLint finished: %d file(s) still have errors.", remaining)))
+    case tooManyWarnings:
+        warnf("\n%s\n", red(fmt.Sprintf("ESLint finished: %d warning(s), more than -max-warnings %d.", warnings, maxWarnings)))
+    default:
+        logln("\n" + green("ESLint finished successfully."))
+    }
+}
+
+// eslintStructured reports whether ESLint's own run should print JSON rather
+// than its human output: a structured report (-format json, -junit or -sarif)
+// needs every file's messages, and capturing them from the fix pass saves
+// running the JSON check pass afterwards.
+func eslintStructured() bool {
+    return outputFormat == "json" || junitPath != "" || sarifPath != ""
+}
+
+// lintChunk runs ESLint over one chunk of files and records per-file results.
+// It returns how many files still have errors, or the error if ESLint could
+// not run at all.
+func lintChunk(eslintBin string, configFlags, baseArgs, chunk []string, out io.Writer) (remaining, warnings int, err error) {
+    if chunk = dropDeleted(chunk, "eslint", out); len(chunk) == 0 {
+        return 0, 0, nil
+    }
+    args := append([]string{}, baseArgs...)
+    structured := eslintStructured()
+    if structured {
+        args = append(args, "--format", "json")
+    }
+    args = append(args, chunk...)
+
+    var captured bytes.Buffer
+    cmd := binCommand(eslintBin, args...)
+    cmd.Dir = repoPath
+    cmd.Stdout = out
+    if structured {
+        cmd.Stdout = &captured
+    }
+    cmd.Stderr = out
+
+    // ESLint exits 1 when lint errors remain and 2 when it could not run at all
+    logCommand(cmd)
+    err = cmd.Run()
+    var exitErr *exec.ExitError
+    switch {
+    case err == nil && maxWarnings < 0 && !structured:
+        for _, f := range chunk {
+            recordResult(fileResult{path: f, tool: "eslint"})
+        }
+        return 0, 0, nil
+    case err == nil || errors.As(err, &exitErr) && exitErr.ExitCode() == 1:
+        // Exit 1: errors remain, or this chunk alone has more than -max-warnings.
+        // A clean exit with -max-warnings still needs the count, since the
+        // limit applies to the whole run rather than to each chunk.
+        if err != nil {
+            setExitStatus(1)
+        }
+        var counts map[string]lintCounts
+        var ok bool
+        if structured {
+            reports, parseErr := parseEslintJSON(captured.Bytes())
+            if ok = parseErr == nil; ok {
+                printLintMessages(out, reports)
+                counts = countsByPath(reports)
+            } else {
+                out.Write(captured.Bytes())
+            }
+        } else {
+            counts, ok = eslintCounts(eslintBin, configFlags, chunk, out)
+        }
+        for _, f := range chunk {
+            if !ok {
+                if err != nil {
+                    recordResult(fileResult{path: f, tool: "eslint", err: errors.New("lint errors remain")})
+                } else {
+                    recordResult(fileResult{path: f, tool: "eslint"})
+                }
+                continue
+            }
+            if counts[f].errors > 0 {
+                remaining++
+            }
+            warnings += counts[f].warnings
+            recordResult(fileResult{path: f, tool: "eslint", errors: counts[f].errors, warnings: counts[f].warnings, messages: counts[f].messages})
+        }
+        return remaining, warnings, nil
+    default:
+        // Whatever ESLint printed is the explanation; don't lose it
+        out.Write(captured.Bytes())
+        failed := 0
+        for _, f := range chunk {
+            if deletedDuringRun(f) {
+                recordResult(fileResult{path: f, tool: "eslint", skipped: true})
+                continue
+            }
+            recordResult(fileResult{path: f, tool: "eslint", err: err})
+            failed++
+        }
+        if failed == 0 {
+            return 0, 0, nil
+        }
+        setExitStatus(2)
+        return 0, 0, err
+    }
+}
+
+// eslintCounts re-runs ESLint (without --fix) using the JSON formatter
+// and returns the remaining errors, warnings and messages per file path.
+func eslintCounts(eslintBin string, configFlags, files []string, stderr io.Writer) (map[string]lintCounts, bool) {
+    args := append(slices.Clone(configFlags), "--format", "json")
+    args = append(args, files...)
+
+    cmd := binCommand(eslintBin, args...)
+    cmd.Dir = repoPath
+    cmd.Stderr = stderr
+    logCommand(cmd)
+    out, _ := cmd.Output()
+
+    reports, err := parseEslintJSON(out)
+    if err != nil {
+        return nil, false
+    }
+    return countsByPath(reports), true
+}
+
+func runHtmlProcessing(files []string) {
+    if noCustomHtml {
+        logf("Processing %d HTML file(s) (Prettier only, -no-custom-html)...\n", len(files))
+    } else {
+        logf("Processing %d HTML file(s) (Prettier + Allman Braces)...\n", len(files))
+    }
+
+    backupOriginals(files)
+
+    // 1. Run Prettier First
+    runPrettier(files)
+
+    // Process each file with the post-processor chain, which skips the
+    // brace expansion for templates that opted out (Prettier still ran above)
+    for _, file := range files {
+        runPostProcessors(file)
+    }
+    if verifyIdempotent {
+        for _, file := range files {
+            checkIdempotent(file)
+        }
+    }
+    tallyHtmlSteps(files)
+    logln(green("HTML processing finished."))
+}
+
+// noCustomHtml turns the Allman pass off for every template (-no-custom-html).
+var noCustomHtml bool
+
+// skipAllman reports whether file keeps Prettier's output without the Allman
+// pass: for every file with -no-custom-html, otherwise when it is listed in
+// .angularformatignore (optOut).
+func skipAllman(file string, optOut *ignoreMatcher) bool {
+    return noCustomHtml || optOut.Match(relPath(file))
+}
+
+// verifyIdempotent re-runs the HTML pipeline on its own output (-verify-idempotent).
+var verifyIdempotent bool
+
+// checkIdempotent runs Prettier, the Allman pass and the post-processors again,
+// in memory, on what the first pass produced and fails the file if that
+// would change it again - a sign that two formatters disagree. Dry runs
+// never wrote the first pass, so it is recomputed from the file as well.
+func checkIdempotent(file string) {
+    content, err := os.ReadFile(file)
+    if err != nil {
+        return
+    }
+    first := string(content)
+    if dryRun {
+        if first, err = htmlPipeline(file, first); err != nil {
+            return
+        }
+    }
+    second, err := htmlPipeline(file, first)
+    // Failures were already reported by the first pass
+    if err != nil || second == first {
+        return
+    }
+    warnf("%s\n", red(fmt.Sprintf("Not idempotent: a second pass would change %s again:", relPath(file))))
+    warnf("%s", unifiedDiff(relPath(file), first, second))
+    recordResult(fileResult{path: file, tool: "idempotency", err: errors.New("second pass changes the output")})
+    setExitStatus(2)
+}
+
+// htmlPipeline is the in-memory equivalent of what runHtmlProcessing does to one file.
+func htmlPipeline(file, content string) (string, error) {
+    out, err := prettierContent(file, content)
+    if err != nil {
+        return "", err
+    }
+    return customHtmlContent(file, out)
+}
+
+// runPrettierOnly handles file kinds that need no custom pass after Prettier.
+func runPrettierOnly(kind string, files []string) {
+    logf("Processing %d %s file(s) (Prettier)...\n", len(files), kind)
+    runPrettier(files)
+    logf("%s processing finished.\n", kind)
+}
+
+// runPrettier formats files in place (or checks them in dry-run mode) and
+// records a result per file. Failures are reported but never stop the run.
+func runPrettier(files []string) {
+    prettierBin, _ := resolveBin(toolRoot(), "prettier")
+
+    baseArgs := configArgs(prettierConfig, ".prettierrc")
+    if dryRun {
+        baseArgs = append(baseArgs, "--check")
+    } else {
+        assertWritable("run prettier --write")
+        baseArgs = append(baseArgs, "--write")
+    }
+    // Drop the per-file listing; "[warn] file" lines from --check still show
+    if quiet {
+        baseArgs = append(baseArgs, "--log-level", "warn")
+    }
+    // Prettier only knows Svelte through its plugin, which lives in toolHome
+    // rather than the repo, so it is passed by path. A project's own Prettier
+    // loads its plugins from the project's config.
+    if !useLocalTools && slices.ContainsFunc(files, func(f string) bool { return extOf(f) == ".svelte" }) {
+        if plugin, ok := prettierPluginPath("prettier-plugin-svelte"); ok {
+            baseArgs = append(baseArgs, "--plugin", plugin)
+        }
+    }
+
+    runChunks(files, func(chunk []string, out io.Writer) {
+        if chunk = dropDeleted(chunk, "prettier", out); len(chunk) == 0 {
+            return
+        }
+        args := append(append([]string{}, baseArgs...), chunk...)
+
+        // --write doesn't say which files it rewrote; compare content hashes
+        before := make(map[string]string)
+        if !dryRun {
+            for _, f := range chunk {
+                before[f], _ = hashFile(f)
+            }
+        }
+
+        // Keep a copy of the output: --check lists unformatted files as "[warn] <path>"
+        var captured bytes.Buffer
+        cmd := binCommand(prettierBin, args...)
+        cmd.Dir = repoPath
+        cmd.Stdout = io.MultiWriter(out, &captured)
+        cmd.Stderr = io.MultiWriter(out, &captured)
+
+        logCommand(cmd)
+        err := cmd.Run()
+        if err != nil {
+            fmt.Fprintf(out, "Prettier encountered a warning/error (continuing): %v\n", err)
+        }
+
+        for _, f := range chunk {
+            result := fileResult{path: f, tool: "prettier"}
+            var exitErr *exec.ExitError
+            switch {
+            case err == nil:
+            case deletedDuringRun(f):
+                // Removed while Prettier ran; not a formatting failure
+                result.skipped = true
+            case dryRun && errors.As(err, &exitErr) && exitErr.ExitCode() == 1:
+                // --check exits 1 only for unformatted files
+                result.changed = prettierNamed(captured.String(), "warn", f)
+                setExitStatus(1)
+            case strings.Contains(captured.String(), "[error] "):
+                // Exit 2: blame the files Prettier named, not the whole chunk
+                if prettierNamed(captured.String(), "error", f) {
+                    result.err = err
+                    setExitStatus(2)
+                }
+            default:
+                result.err = err
+                setExitStatus(2)
+            }
+            if !dryRun && !result.skipped {
+                after, _ := hashFile(f)
+                result.changed = after != before[f]
+            }
+            recordResult(result)
+        }
+    })
+}
+
+// prettierNamed reports whether Prettier's output has a "[level] <path>" line
+// for file. Prettier may print the path as given or relative to the repo, and
+// with either separator on Windows, so all forms are compared with slashes.
+func prettierNamed(output, level, file string) bool {
+    output = filepath.ToSlash(output)
+    for _, name := range []string{filepath.ToSlash(file), relPath(file)} {
+        if strings.Contains(output, "["+level+"] "+name) {
+            return true
+        }
+    }
+    return false
+}
+
+// indentUnit is the indent added per brace depth (-indent). 4 spaces by default.
+var indentUnit = "    "
+
+// finalNewline adds a trailing newline to templates that lack one
+// (-final-newline). A newline that is already there is always kept.
+var finalNewline = true
+
+// braceStyle places the '{' that opens a control flow block (-brace-style):
+// braceAllman on a line of its own, braceKR at the end of the directive line.
+// Closing braces are on their own line either way.
+var braceStyle = braceAllman
+
+const (
+    braceAllman = angular.BraceAllman
+    braceKR     = angular.BraceKR
+)
+
+// maxBlankLines caps a run of consecutive blank lines in the custom HTML pass
+// (-max-blank-lines). Blank lines inside <pre>, <textarea> and comments are
+// content and are never collapsed. Negative keeps every blank line.
+var maxBlankLines = 1
+
+// --- UTILITIES ---
+
+// findForkPoint picks the branch the current branch was created from. A
+// configured upstream that is another branch says so directly and wins.
+// Otherwise the merge-base with each default branch is tried since it works
+// on fresh clones; the reflog is only consulted when no merge-base can be
+// computed.
+func findForkPoint(currentBranch string) string {
+    if parent := upstreamBranch(currentBranch); parent != "" {
+        return parent
+    }
+    if parent := closestMergeBase(currentBranch); parent != "" {
+        return parent
+    }
+
+    reflogOut := getCommandOutput("git", "reflog", "--date=iso")
+    lines := strings.Split(reflogOut, "\n")
+    for _, line := range lines {
+        if strings.Contains(line, "moving from ") && strings.Contains(line, fmt.Sprintf(" to %s", currentBranch)) {
+            parts := strings.Split(line, "moving from ")
+            if len(parts) > 1 {
+                remainder := parts[1]
+                toParts := strings.Split(remainder, " to ")
+                candidate := strings.TrimSpace(toParts[0])
+                if isSameBranch(candidate, currentBranch) {
+                    continue
+                }
+                verbosef("Fork point from reflog: %s", candidate)
+                return candidate
+            }
+        }
+    }
+    verbosef("No checkout of '%s' found in reflog; defaulting to 'main'.", currentBranch)
+    return "main"
+}
+
+// upstreamBranch returns the current branch's upstream (e.g. origin/main for
+// a branch created with "git checkout -b feature origin/main"), or "" if it
+// has none, if the upstream is just the same branch on a remote (the usual
+// "git push -u"), or if it shares no history with HEAD. The diff against it
+// starts at their merge-base like any other parent.
+func upstreamBranch(currentBranch string) string {
+    upstream := getCommandOutput("git", "rev-parse", "--abbrev-ref", "--symbolic-full-name", "@{upstream}")
+    if upstream == "" {
+        return ""
+    }
+    if isSameBranch(upstream, currentBranch) {
+        verbosef("Skipping upstream '%s': same as current branch.", upstream)
+        return ""
+    }
+    if getCommandOutput("git", "merge-base", upstream, "HEAD") == "" {
+        verbosef("Skipping upstream '%s': no merge-base with HEAD.", upstream)
+        return ""
+    }
+    verbosef("Fork point from upstream: %s", upstream)
+    return upstream
+}
+
+// forkPointCandidates are the branches a feature branch is usually created from.
+var forkPointCandidates = []string{"main", "master", "develop", "origin/main", "origin/master", "origin/develop"}
+
+// closestMergeBase returns the candidate whose merge-base with HEAD is the
+// fewest commits behind HEAD, or "" if none has one. Earlier candidates win
+// ties, so a develop that hasn't moved since branching from main yields main.
+func closestMergeBase(currentBranch string) string {
+    best := ""
+    bestDistance := -1
+    for _, c := range forkPointCandidates {
+        if !isValidRef(c) {
+            verbosef("Skipping '%s': ref does not exist.", c)
+            continue
+        }
+        if isSameBranch(c, currentBranch) {
+            verbosef("Skipping '%s': same as current branch.", c)
+            continue
+        }
+        // --fork-point uses the candidate's reflog to see through rebases;
+        // a plain merge-base covers clones where that reflog is missing
+        base := getCommandOutput("git", "merge-base", "--fork-point", c, "HEAD")
+        if base == "" {
+            base = getCommandOutput("git", "merge-base", c, "HEAD")
+        }
+        if base == "" {
+            verbosef("Skipping '%s': no merge-base with HEAD.", c)
+            continue
+        }
+        distance, err := strconv.Atoi(getCommandOutput("git", "rev-list", "--count", base+"..HEAD"))
+        if err != nil {
+            continue
+        }
+        verbosef("Merge-base with '%s' is %d commit(s) behind HEAD.", c, distance)
+        if bestDistance < 0 || distance < bestDistance {
+            best, bestDistance = c, distance
+        }
+    }
+    if best != "" {
+        verbosef("Fork point from merge-base: %s", best)
+    }
+    return best
+}
+
+func isSameBranch(candidate, current string) bool {
+    if candidate == current || candidate == "origin/"+current {
+        return true
+    }
+    if strings.HasSuffix(candidate, "/"+current) {
+        return true
+    }
+    return false
+}
+
+// resolveParentRef returns the full ref name for the parent branch (e.g.
+// refs/remotes/origin/main), so the diff cannot pick a same-named tag or
+// branch instead. A remote-tracking parent is only as fresh as the last
+// fetch: it is fetched first with -fetch, otherwise a warning says so.
+func resolveParentRef(parent string, fetch bool) string {
+    full := getCommandOutput("git", "rev-parse", "--verify", "--quiet", "--symbolic-full-name", parent)
+    if full == "" {
+        // A commit hash or an expression like HEAD~3 has no ref name
+        return parent
+    }
+    tracking, isRemote := strings.CutPrefix(full, "refs/remotes/")
+    if !isRemote {
+        return full
+    }
+
+    remote, branch := splitRemoteRef(tracking)
+    if remote == "" {
+        return full
+    }
+    if !fetch {
+        warnf("Note: %s is a remote-tracking branch; comparing against its state at the last fetch (pass -fetch to update it first).\n", parent)
+        return full
+    }
+
+    logf("Fetching %s from %s...\n", branch, remote)
+    if out, err := runner.Output("git", "fetch", "--quiet", remote, fmt.Sprintf("+refs/heads/%s:%s", branch, full)); err != nil {
+        warnf("Warning: git fetch %s %s failed (%v); comparing against the last fetched state.\n%s", remote, branch, err, out)
+    }
+    return full
+}
+
+// splitRemoteRef splits "origin/feature/x" into the configured remote it
+// belongs to and the branch on that remote. The longest matching remote name
+// wins, since remote names may themselves contain slashes.
+func splitRemoteRef(tracking string) (remote, branch string) {
+    for _, r := range strings.Split(getCommandOutput("git", "remote"), "\n") {
+        if r != "" && strings.HasPrefix(tracking, r+"/") && len(r) > len(remote) {
+            remote = r
+        }
+    }
+    if remote == "" {
+        return "", ""
+    }
+    return remote, strings.TrimPrefix(tracking, remote+"/")
+}
+
+func isValidRef(ref string) bool {
+    _, err := runner.Output("git", "rev-parse", "--verify", ref)
+    return err == nil
+}
+
+func getCommandOutput(name string, args ...string) string {
+    out, err := runner.Output(name, args...)
+    if err != nil {
+        return ""
+    }
+    return strings.TrimSpace(string(out))
+}
+
+// commandRunner runs the git queries behind diff and fork-point detection.
+// Swapping runner for a fake that returns canned output lets that logic be
+// exercised without a real repository.
+type commandRunner interface {
+    // Output runs name with args in repoPath and returns stdout and stderr combined.
+    Output(name string, args ...string) ([]byte, error)
+}
+
+// execRunner runs real processes, with -timeout and -verbose logging.
+type execRunner struct{}
+
+func (execRunner) Output(name string, args ...string) ([]byte, error) {
+    cmd := newTimedCmd(toolTimeout, name, args...)
+    cmd.Dir = repoPath
+    logCommand(cmd)
+    return cmd.CombinedOutput()
+}
+
+var runner commandRunner = execRunner{}
+
+// logOut receives the tool's progress messages and the output of the tools it
+// runs. It is stdout, except with -format json where stdout is reserved for
+// the JSON report and everything else goes to stderr.
+var logOut io.Writer = os.Stdout
+
+// quiet suppresses informational messages; warnings and errors still print.
+var quiet bool
+
+// logf and logln print informational progress messages, hidden by -quiet.
+func logf(format string, args ...any) {
+    if quiet {
+        return
+    }
+    fmt.Fprintf(logOut, format, args...)
+}
+
+func logln(args ...any) {
+    if quiet {
+        return
+    }
+    fmt.Fprintln(logOut, args...)
+}
+
+// warnf prints warnings, errors and anything that affects the exit status.
+// It is never silenced.
+func warnf(format string, args ...any) {
+    fmt.Fprintf(logOut, format, args...)
+}
+
+// verbosef prints a diagnostic line to stderr when -verbose is set.
+func verbosef(format string, args ...any) {
+    if !verbose {
+        return
+    }
+    fmt.Fprintf(os.Stderr, "[verbose] "+format+"\n", args...)
+}
+
+// logCommand prints an external command and its working directory with -verbose.
+func logCommand(cmd *timedCmd) {
+    if !verbose {
+        return
+    }
+    parts := make([]string, len(cmd.Args))
+    for i, a := range cmd.Args {
+        if a == "" || strings.ContainsAny(a, " \t\"'") {
+            a = strconv.Quote(a)
+        }
+        parts[i] = a
+    }
+    verbosef("exec (in %s): %s", cmd.Dir, strings.Join(parts, " "))
 }
\ No newline at end of file
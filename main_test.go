@@ -0,0 +1,306 @@
+package main
+
+import (
+    "encoding/json"
+    "errors"
+    "io"
+    "os"
+    "path/filepath"
+    "strings"
+    "testing"
+)
+
+// fakeRunner answers commands from a table keyed by the full command line;
+// anything not in it fails, like git does for a missing ref.
+type fakeRunner map[string]string
+
+func (f fakeRunner) Output(name string, args ...string) ([]byte, error) {
+    key := strings.Join(append([]string{name}, args...), " ")
+    out, ok := f[key]
+    if !ok {
+        return []byte("fatal: " + key), errors.New("exit status 128")
+    }
+    return []byte(out + "\n"), nil
+}
+
+// useRunner swaps in a fake for the duration of a test.
+func useRunner(t *testing.T, f commandRunner) {
+    t.Helper()
+    saved := runner
+    runner = f
+    t.Cleanup(func() { runner = saved })
+}
+
+// withRef adds what the fork-point logic asks git about a candidate ref: that
+// it exists, its merge-base with HEAD and how far behind HEAD that is.
+func withRef(f fakeRunner, ref, base, distance string) fakeRunner {
+    f["git rev-parse --verify "+ref] = base
+    f["git merge-base "+ref+" HEAD"] = base
+    f["git rev-list --count "+base+"..HEAD"] = distance
+    return f
+}
+
+func TestIsSameBranch(t *testing.T) {
+    tests := []struct {
+        candidate, current string
+        want               bool
+    }{
+        {"feature", "feature", true},
+        {"origin/feature", "feature", true},
+        {"upstream/feature", "feature", true},
+        {"main", "feature", false},
+        {"origin/main", "feature", false},
+        {"origin/my-feature", "feature", false},
+        {"main", "HEAD", false},
+    }
+    for _, tt := range tests {
+        if got := isSameBranch(tt.candidate, tt.current); got != tt.want {
+            t.Errorf("isSameBranch(%q, %q) = %t, want %t", tt.candidate, tt.current, got, tt.want)
+        }
+    }
+}
+
+func TestUpstreamBranch(t *testing.T) {
+    const upstreamCmd = "git rev-parse --abbrev-ref --symbolic-full-name @{upstream}"
+    tests := []struct {
+        name    string
+        current string
+        git     fakeRunner
+        want    string
+    }{
+        {"no upstream", "feature", fakeRunner{}, ""},
+        {"detached HEAD", "HEAD", fakeRunner{}, ""},
+        {"same branch on a remote", "feature", fakeRunner{upstreamCmd: "origin/feature"}, ""},
+        {"another branch", "feature", withRef(fakeRunner{upstreamCmd: "origin/main"}, "origin/main", "abc", "2"), "origin/main"},
+        {"no shared history", "feature", fakeRunner{upstreamCmd: "origin/orphan"}, ""},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            useRunner(t, tt.git)
+            if got := upstreamBranch(tt.current); got != tt.want {
+                t.Errorf("upstreamBranch(%q) = %q, want %q", tt.current, got, tt.want)
+            }
+        })
+    }
+}
+
+func TestClosestMergeBase(t *testing.T) {
+    tests := []struct {
+        name    string
+        current string
+        git     fakeRunner
+        want    string
+    }{
+        {"no candidates", "feature", fakeRunner{}, ""},
+        {"only main", "feature", withRef(fakeRunner{}, "main", "m1", "5"), "main"},
+        {
+            "develop is closer",
+            "feature",
+            withRef(withRef(fakeRunner{}, "main", "m1", "5"), "develop", "d1", "2"),
+            "develop",
+        },
+        {
+            "tie goes to the earlier candidate",
+            "feature",
+            withRef(withRef(fakeRunner{}, "main", "m1", "3"), "develop", "m1", "3"),
+            "main",
+        },
+        {
+            "current branch is skipped",
+            "develop",
+            withRef(withRef(fakeRunner{}, "main", "m1", "5"), "develop", "d1", "0"),
+            "main",
+        },
+        {
+            "fork-point preferred over plain merge-base",
+            "feature",
+            withRef(withRef(fakeRunner{
+                "git merge-base --fork-point main HEAD": "f1",
+                "git rev-list --count f1..HEAD":         "1",
+            }, "main", "m1", "9"), "develop", "d1", "2"),
+            "main",
+        },
+        {"ref without a merge-base", "feature", fakeRunner{"git rev-parse --verify main": "m1"}, ""},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            useRunner(t, tt.git)
+            if got := closestMergeBase(tt.current); got != tt.want {
+                t.Errorf("closestMergeBase(%q) = %q, want %q", tt.current, got, tt.want)
+            }
+        })
+    }
+}
+
+func TestFindForkPoint(t *testing.T) {
+    const upstreamCmd = "git rev-parse --abbrev-ref --symbolic-full-name @{upstream}"
+    tests := []struct {
+        name    string
+        current string
+        git     fakeRunner
+        want    string
+    }{
+        {
+            "upstream wins over a closer merge-base",
+            "feature",
+            withRef(withRef(fakeRunner{upstreamCmd: "origin/main"}, "origin/main", "m1", "5"), "develop", "d1", "1"),
+            "origin/main",
+        },
+        {
+            "no upstream uses the closest merge-base",
+            "feature",
+            withRef(withRef(fakeRunner{}, "main", "m1", "5"), "develop", "d1", "1"),
+            "develop",
+        },
+        {
+            "detached HEAD uses the closest merge-base",
+            "HEAD",
+            withRef(fakeRunner{}, "master", "m1", "4"),
+            "master",
+        },
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            useRunner(t, tt.git)
+            if got := findForkPoint(tt.current); got != tt.want {
+                t.Errorf("findForkPoint(%q) = %q, want %q", tt.current, got, tt.want)
+            }
+        })
+    }
+}
+
+func TestDiffLineRenames(t *testing.T) {
+    tests := []struct {
+        line, want string
+        tool       string
+    }{
+        {"src/app/a.component.html", "src/app/a.component.html", "html"},
+        {"12\t3\tsrc/a.ts", "src/a.ts", "eslint"},
+        {"-\t-\timg.png", "img.png", ""},
+        {"R100\tsrc/old.html\tsrc/new.html", "src/new.html", "html"},
+        {"R087\tsrc/old.ts\tsrc/renamed.component.ts", "src/renamed.component.ts", "eslint"},
+        {"C75\tsrc/a.scss\tsrc/b.scss", "src/b.scss", "style"},
+        {"M\tsrc/a.ts", "src/a.ts", "eslint"},
+        {"A\tdocs/readme.md", "docs/readme.md", "markdown"},
+        {"5\t1\told.html => new.html", "new.html", "html"},
+        {"5\t1\tsrc/{old => new}/list.component.html", "src/new/list.component.html", "html"},
+        {"0\t0\tsrc/{ => sub}/a.ts", "src/sub/a.ts", "eslint"},
+        {`"src/caf\303\251.html"`, "src/café.html", "html"},
+        {"R100\t\"src/tab\\there.ts\"\t\"src/with space \\\"q\\\".ts\"", `src/with space "q".ts`, "eslint"},
+        {"3\t3\t\"src/na\\303\\257ve.ts\"", "src/naïve.ts", "eslint"},
+    }
+    for _, tt := range tests {
+        got := renameTarget(diffLinePath(tt.line))
+        if got != tt.want {
+            t.Errorf("renameTarget(diffLinePath(%q)) = %q, want %q", tt.line, got, tt.want)
+            continue
+        }
+        if tool := toolFor(extOf(got)); tool != tt.tool {
+            t.Errorf("%q is routed to %q, want %q", got, tool, tt.tool)
+        }
+    }
+}
+
+// TestFindForkPointWithoutMergeBase covers clones where no default branch
+// shares history with HEAD, so only the reflog is left to ask.
+func TestFindForkPointWithoutMergeBase(t *testing.T) {
+    const reflogCmd = "git reflog --date=iso"
+    tests := []struct {
+        name string
+        git  fakeRunner
+        want string
+    }{
+        {
+            "reflog names the parent",
+            fakeRunner{reflogCmd: "abc HEAD@{2026-01-01 10:00:00 +0000}: checkout: moving from release to feature"},
+            "release",
+        },
+        {
+            "checkout from the branch's own remote copy is skipped",
+            fakeRunner{reflogCmd: "abc HEAD@{2026-01-02 10:00:00 +0000}: checkout: moving from origin/feature to feature\n" +
+                "def HEAD@{2026-01-01 10:00:00 +0000}: checkout: moving from develop to feature"},
+            "develop",
+        },
+        {
+            "checkouts of other branches are ignored",
+            fakeRunner{reflogCmd: "abc HEAD@{2026-01-01 10:00:00 +0000}: checkout: moving from main to other"},
+            "main",
+        },
+        {"expired reflog defaults to main", fakeRunner{reflogCmd: ""}, "main"},
+        {"no reflog at all defaults to main", fakeRunner{}, "main"},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            useRunner(t, tt.git)
+            if got := findForkPoint("feature"); got != tt.want {
+                t.Errorf("findForkPoint(\"feature\") = %q, want %q", got, tt.want)
+            }
+        })
+    }
+}
+
+func TestScopeToPath(t *testing.T) {
+    root := t.TempDir()
+    for _, dir := range []string{"foo", "foobar"} {
+        if err := os.Mkdir(filepath.Join(root, dir), 0755); err != nil {
+            t.Fatal(err)
+        }
+    }
+    savedRepo, savedRoot, savedOut := repoPath, gitRoot, logOut
+    repoPath, gitRoot, logOut = filepath.Join(root, "foo"), root, io.Discard
+    t.Cleanup(func() { repoPath, gitRoot, logOut = savedRepo, savedRoot, savedOut })
+
+    tests := []struct {
+        path string
+        want string // "" when the path is outside the scope
+    }{
+        {"foo/a.component.html", filepath.Join(root, "foo", "a.component.html")},
+        {"foo/sub/b.ts", filepath.Join(root, "foo", "sub", "b.ts")},
+        {"foobar/c.ts", ""},
+        {"foo.ts", ""},
+        {"d.scss", ""},
+        {"other/foo/e.ts", ""},
+    }
+    for _, tt := range tests {
+        got := scopeToPath([]string{tt.path})
+        switch {
+        case tt.want == "" && len(got) != 0:
+            t.Errorf("scopeToPath(%q) = %q, want it dropped", tt.path, got)
+        case tt.want != "" && (len(got) != 1 || got[0] != tt.want):
+            t.Errorf("scopeToPath(%q) = %q, want [%q]", tt.path, got, tt.want)
+        }
+    }
+}
+
+// TestListAsJSON checks that -list keeps stdout a single JSON object under
+// -format json.
+func TestListAsJSON(t *testing.T) {
+    savedRepo, savedFormat, savedStdout := repoPath, outputFormat, os.Stdout
+    repoPath, outputFormat = t.TempDir(), "json"
+    r, w, err := os.Pipe()
+    if err != nil {
+        t.Fatal(err)
+    }
+    os.Stdout = w
+    t.Cleanup(func() { repoPath, outputFormat, os.Stdout = savedRepo, savedFormat, savedStdout })
+
+    printFileLists([]fileList{
+        {"ESLint", nil},
+        {"HTML", []string{filepath.Join(repoPath, "src", "a.component.html")}},
+    })
+    w.Close()
+    out, _ := io.ReadAll(r)
+
+    var got struct {
+        Lists []struct {
+            Tool  string   `json:"tool"`
+            Files []string `json:"files"`
+        } `json:"lists"`
+    }
+    if err := json.Unmarshal(out, &got); err != nil {
+        t.Fatalf("stdout is not JSON: %v\n%s", err, out)
+    }
+    if len(got.Lists) != 1 || got.Lists[0].Tool != "HTML" || len(got.Lists[0].Files) != 1 || got.Lists[0].Files[0] != "src/a.component.html" {
+        t.Errorf("lists = %+v", got.Lists)
+    }
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+    "fmt"
+    "strings"
+)
+
+// --- MARKDOWN CODE FENCES ---
+
+// fenceLanguages maps a fence's info string language to the extension whose
+// formatter handles the block. Blocks in any other language (or none) are
+// left exactly as written.
+var fenceLanguages = map[string]string{
+    "ts": ".ts", "typescript": ".ts", "tsx": ".tsx",
+    "js": ".js", "javascript": ".js", "jsx": ".jsx",
+    "html": ".html", "angular": ".html",
+    "css": ".css", "scss": ".scss", "less": ".less",
+    "json": ".json", "yaml": ".yaml", "yml": ".yaml",
+    "go": ".go",
+}
+
+// runMarkdown formats the fenced code blocks of Markdown files in place; the
+// prose around them is never touched.
+func runMarkdown(files []string) {
+    logf("Formatting code fences in %d Markdown file(s)...\n", len(files))
+    for _, file := range files {
+        applyTransform(file, "markdown", func(src []byte) ([]byte, error) {
+            out, err := formatMarkdownFences(file, string(src))
+            return []byte(out), err
+        })
+    }
+    logln(green("Markdown processing finished."))
+}
+
+// codeFence is an open fenced code block.
+type codeFence struct {
+    marker string // the opening run of ` or ~, e.g. "````"
+    indent string // whitespace before the marker, stripped from the content
+    ext    string // formatter extension, "" for a language we don't format
+    line   int    // 1-based line of the opening fence
+    body   []string
+}
+
+// formatMarkdownFences reformats the content of every fenced block in a
+// known language. Fence lines are copied unchanged and each content line
+// keeps the fence's indentation (e.g. inside a list item). A fence is only
+// closed by a line of the same character at least as long as the opening,
+// so a ```` block can show ``` fences as content. A block that fails to
+// format (an incomplete snippet, say) is kept as it is, with a warning.
+func formatMarkdownFences(file, content string) (string, error) {
+    crlf := strings.Contains(content, "\r\n")
+    if crlf {
+        content = strings.ReplaceAll(content, "\r\n", "\n")
+    }
+
+    lines := strings.Split(content, "\n")
+    var result []string
+    var fence *codeFence
+    for i, line := range lines {
+        if fence == nil {
+            result = append(result, line)
+            if indent, marker, info, ok := parseFence(line); ok {
+                // "```ts title=x.ts" and "```{.ts}" both name the language first
+                lang, _, _ := strings.Cut(info, " ")
+                lang = strings.ToLower(strings.Trim(lang, "{}."))
+                fence = &codeFence{marker: marker, indent: indent, ext: fenceLanguages[lang], line: i + 1}
+            }
+            continue
+        }
+
+        if closesFence(line, fence.marker) {
+            result = append(result, formatFence(file, fence)...)
+            result = append(result, line)
+            fence = nil
+            continue
+        }
+        fence.body = append(fence.body, line)
+    }
+    // An unclosed fence runs to the end of the document; leave it alone
+    if fence != nil {
+        result = append(result, fence.body...)
+    }
+
+    out := strings.Join(result, "\n")
+    if crlf {
+        out = strings.ReplaceAll(out, "\n", "\r\n")
+    }
+    return out, nil
+}
+
+// parseFence recognizes an opening fence: optional indentation, then at
+// least three backticks or tildes, then the info string.
+func parseFence(line string) (indent, marker, info string, ok bool) {
+    rest := strings.TrimLeft(line, " \t")
+    indent = line[:len(line)-len(rest)]
+    if len(rest) < 3 || (rest[0] != '`' && rest[0] != '~') {
+        return "", "", "", false
+    }
+    n := len(rest) - len(strings.TrimLeft(rest, rest[:1]))
+    info = strings.TrimSpace(rest[n:])
+    // A backtick in the info string makes it inline code, not a fence
+    if n < 3 || (rest[0] == '`' && strings.Contains(info, "`")) {
+        return "", "", "", false
+    }
+    return indent, rest[:n], info, true
+}
+
+// closesFence reports whether line ends the fence opened with marker.
+func closesFence(line, marker string) bool {
+    rest := strings.TrimSpace(line)
+    return len(rest) >= len(marker) && strings.Trim(rest, marker[:1]) == ""
+}
+
+// formatFence returns the block's lines, formatted if it is in a known
+// language, re-indented to the fence.
+func formatFence(file string, fence *codeFence) []string {
+    if fence.ext == "" || strings.TrimSpace(strings.Join(fence.body, "")) == "" {
+        return fence.body
+    }
+
+    code := make([]string, len(fence.body))
+    for i, line := range fence.body {
+        code[i] = stripIndent(line, fence.indent)
+    }
+    formatted, err := formatSnippet(file, fence.ext, strings.Join(code, "\n")+"\n")
+    if err != nil {
+        warnf("%s\n", yellow(fmt.Sprintf("Warning: %s:%d: %s code block left unchanged: %v", relPath(file), fence.line, strings.TrimPrefix(fence.ext, "."), err)))
+        return fence.body
+    }
+
+    var out []string
+    for _, line := range strings.Split(strings.TrimRight(formatted, "\n"), "\n") {
+        if line != "" {
+            line = fence.indent + line
+        }
+        out = append(out, line)
+    }
+    return out
+}
+
+// stripIndent removes the fence's indentation from a content line, or as
+// much of it as the line has (CommonMark allows content indented less).
+func stripIndent(line, indent string) string {
+    if rest, ok := strings.CutPrefix(line, indent); ok {
+        return rest
+    }
+    return strings.TrimLeft(line, " \t")
+}
+
+// formatSnippet formats one block as if it were a file with extension ext
+// next to the Markdown file, so Prettier picks the parser and the project's
+// config from the path. HTML blocks get the Allman pass after Prettier.
+func formatSnippet(file, ext, code string) (string, error) {
+    if f, ok := formatters[ext]; ok && ext != ".html" {
+        out, err := f.Format([]byte(code))
+        return string(out), err
+    }
+    out, err := prettierContent(file+ext, code)
+    if err != nil || ext != ".html" || noCustomHtml {
+        return out, err
+    }
+    formatted, err := htmlFormatter().Format([]byte(out))
+    return string(formatted), err
+}
@@ -0,0 +1,67 @@
+package main
+
+import "testing"
+
+func TestFormatMarkdownFences(t *testing.T) {
+    tests := []struct {
+        name     string
+        in, want string
+    }{
+        {
+            "go block",
+            "# Title\n\n```go\nfunc f(){\nreturn\n}\n```\n",
+            "# Title\n\n```go\nfunc f() {\n\treturn\n}\n```\n",
+        },
+        {
+            "unknown language is left alone",
+            "```text\nfunc f(){\n```\n",
+            "```text\nfunc f(){\n```\n",
+        },
+        {
+            "tilde line inside a backtick fence",
+            "```text\n~~~\n```go\nvar a=1\n```\n",
+            "```text\n~~~\n```go\nvar a=1\n```\n",
+        },
+        {
+            "backtick line inside a tilde fence",
+            "~~~md\n```\n~~~\n```go\nvar a=1\n```\n",
+            "~~~md\n```\n~~~\n```go\nvar a = 1\n```\n",
+        },
+        {
+            "indented fence in a list item",
+            "- step:\n\n   ```go\n   func f(){\n   return\n   }\n   ```\n",
+            "- step:\n\n   ```go\n   func f() {\n   \treturn\n   }\n   ```\n",
+        },
+        {
+            "longer closing fence",
+            "```go\nvar a=1\n`````\nvar b=2\n",
+            "```go\nvar a = 1\n`````\nvar b=2\n",
+        },
+        {
+            "shorter run does not close",
+            "````go\nvar a=1\n```\n````\n",
+            "````go\nvar a=1\n```\n````\n",
+        },
+        {
+            "unclosed fence",
+            "```go\nvar a=1\n",
+            "```go\nvar a=1\n",
+        },
+        {
+            "crlf",
+            "```go\r\nvar a=1\r\n```\r\n",
+            "```go\r\nvar a = 1\r\n```\r\n",
+        },
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            got, err := formatMarkdownFences("README.md", tt.in)
+            if err != nil {
+                t.Fatal(err)
+            }
+            if got != tt.want {
+                t.Errorf("got:\n%q\nwant:\n%q", got, tt.want)
+            }
+        })
+    }
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+    "bytes"
+    "fmt"
+    "io"
+    "os"
+    "sync"
+)
+
+// --- WORKER POOL ---
+
+// jobs is the number of tool processes allowed to run at once (-jobs).
+var jobs int
+
+// maxChunkSize caps files per invocation so command lines stay well under
+// the Windows limit (~32K characters) even with long absolute paths.
+const maxChunkSize = 50
+
+var outputMu sync.Mutex
+
+// memBudget is a soft limit in MB on the estimated memory of in-flight tool
+// processes (-mem-budget). Zero disables admission control.
+var memBudget int
+
+// Rough cost model for one ESLint/Prettier process: a fixed Node baseline plus
+// a multiple of the source size for parsed ASTs and fix passes.
+const (
+    processBaseCost = 150 << 20
+    fileCostFactor  = 20
+)
+
+// admission throttles worker launches so the estimated memory of running
+// chunks stays within the budget. A chunk larger than the whole budget is
+// still admitted once nothing else is running, so progress is guaranteed.
+type admission struct {
+    mu      sync.Mutex
+    cond    *sync.Cond
+    budget  int64
+    inUse   int64
+    running int
+}
+
+func newAdmission(budgetMB int) *admission {
+    a := &admission{budget: int64(budgetMB) << 20}
+    a.cond = sync.NewCond(&a.mu)
+    return a
+}
+
+func (a *admission) acquire(cost int64) {
+    a.mu.Lock()
+    defer a.mu.Unlock()
+    for a.running > 0 && a.inUse+cost > a.budget {
+        a.cond.Wait()
+    }
+    a.inUse += cost
+    a.running++
+}
+
+func (a *admission) release(cost int64) {
+    a.mu.Lock()
+    defer a.mu.Unlock()
+    a.inUse -= cost
+    a.running--
+    a.cond.Broadcast()
+}
+
+// chunkCost estimates the memory needed to process a chunk.
+func chunkCost(chunk []string) int64 {
+    cost := int64(processBaseCost)
+    for _, f := range chunk {
+        if info, err := os.Stat(f); err == nil {
+            cost += info.Size() * fileCostFactor
+        }
+    }
+    return cost
+}
+
+// chunkFiles splits files so that every worker gets work, without any chunk
+// exceeding maxChunkSize.
+func chunkFiles(files []string) [][]string {
+    if perFile {
+        chunks := make([][]string, len(files))
+        for i, f := range files {
+            chunks[i] = []string{f}
+        }
+        return chunks
+    }
+    workers := jobs
+    if workers < 1 {
+        workers = 1
+    }
+    size := (len(files) + workers - 1) / workers
+    if size > maxChunkSize {
+        size = maxChunkSize
+    }
+    if size < 1 {
+        size = 1
+    }
+
+    var chunks [][]string
+    for start := 0; start < len(files); start += size {
+        end := start + size
+        if end > len(files) {
+            end = len(files)
+        }
+        chunks = append(chunks, files[start:end])
+    }
+    return chunks
+}
+
+// perFile runs ESLint/Prettier once per file and prints a header before each
+// file's output (-per-file), trading speed for unambiguous error attribution.
+var perFile bool
+
+// withFileHeader prefixes the output of a single-file chunk with
+// "=== path ===". With -quiet the header only appears if the tool said something.
+func withFileHeader(fn func(chunk []string, out io.Writer)) func(chunk []string, out io.Writer) {
+    return func(chunk []string, out io.Writer) {
+        var buf bytes.Buffer
+        fn(chunk, &buf)
+        if buf.Len() == 0 && quiet {
+            return
+        }
+        fmt.Fprintf(out, "=== %s ===\n", relPath(chunk[0]))
+        out.Write(buf.Bytes())
+    }
+}
+
+// runChunks calls fn for every chunk of files on up to jobs concurrent workers.
+// Each call writes into its own buffer, which is flushed to stdout in one piece
+// when the call returns so output from different workers never interleaves.
+func runChunks(files []string, fn func(chunk []string, out io.Writer)) {
+    if perFile {
+        fn = withFileHeader(fn)
+    }
+    chunks := chunkFiles(files)
+    if len(chunks) == 1 {
+        fn(chunks[0], logOut)
+        return
+    }
+
+    var budget *admission
+    if memBudget > 0 {
+        budget = newAdmission(memBudget)
+    }
+
+    sem := make(chan struct{}, max(jobs, 1))
+    var wg sync.WaitGroup
+    for _, chunk := range chunks {
+        wg.Add(1)
+        sem <- struct{}{}
+        cost := chunkCost(chunk)
+        if budget != nil {
+            budget.acquire(cost)
+        }
+        go func(chunk []string) {
+            defer wg.Done()
+            defer func() { <-sem }()
+            if budget != nil {
+                defer budget.release(cost)
+            }
+
+            var buf bytes.Buffer
+            fn(chunk, &buf)
+
+            outputMu.Lock()
+            logOut.Write(buf.Bytes())
+            outputMu.Unlock()
+        }(chunk)
+    }
+    wg.Wait()
+}
@@ -0,0 +1,72 @@
+package main
+
+import "fmt"
+
+// --- POST-PROCESSORS ---
+
+// PostProcessor is a transformation of an HTML template that runs after
+// Prettier: the built-in Allman pass, then team-specific ones such as sorting
+// Tailwind classes. path is the absolute path of the file; the returned
+// content replaces it.
+type PostProcessor func(path, content string) (string, error)
+
+type namedPostProcessor struct {
+    name string
+    run  PostProcessor
+}
+
+// postProcessors run in registration order, each on the previous one's output.
+var postProcessors []namedPostProcessor
+
+func init() {
+    registerPostProcessor("angular", allmanPass)
+}
+
+// registerPostProcessor appends p to the chain under a display name (used in
+// error messages). Call it from an init func, one file per processor:
+//
+//    func init() {
+//        registerPostProcessor("tailwind", sortTailwindClasses)
+//    }
+//
+// Init funcs run in file name order, after this file's for any file named
+// later, so team processors see the Allman pass's output.
+func registerPostProcessor(name string, p PostProcessor) {
+    postProcessors = append(postProcessors, namedPostProcessor{name: name, run: p})
+}
+
+// allmanPass is the built-in processor: the brace expansion, unless the
+// template is listed in .angularformatignore or -no-custom-html is set.
+func allmanPass(path, content string) (string, error) {
+    if skipAllman(path, angularOptOut()) {
+        if !noCustomHtml {
+            verbosef("Skipping custom formatter for %s: matched by %s.", relPath(path), angularIgnoreFileName)
+        }
+        return content, nil
+    }
+    out, err := htmlFormatter().Format([]byte(content))
+    return string(out), err
+}
+
+// runPostProcessors rewrites file with the whole chain, once. The content is
+// passed from one processor to the next in memory, so a dry run diffs what
+// the chain as a whole would write. A failing processor fails the file and
+// leaves it unchanged; other files still run.
+func runPostProcessors(file string) {
+    applyTransform(file, htmlFormatter().name, func(src []byte) ([]byte, error) {
+        out, err := customHtmlContent(file, string(src))
+        return []byte(out), err
+    })
+}
+
+// customHtmlContent runs content through every registered processor in order.
+func customHtmlContent(file, content string) (string, error) {
+    for _, p := range postProcessors {
+        out, err := p.run(file, content)
+        if err != nil {
+            return "", fmt.Errorf("%s: %w", p.name, err)
+        }
+        content = out
+    }
+    return content, nil
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+    "bytes"
+    "os"
+    "path/filepath"
+    "slices"
+    "strings"
+    "testing"
+)
+
+func TestPostProcessorChainInDryRun(t *testing.T) {
+    dir := t.TempDir()
+    var out bytes.Buffer
+    savedRepo, savedOut, savedStatus, savedDry, savedChain := repoPath, logOut, exitStatus, dryRun, postProcessors
+    repoPath, logOut, exitStatus, dryRun = dir, &out, 0, true
+    postProcessors = slices.Clone(postProcessors)
+    t.Cleanup(func() {
+        repoPath, logOut, exitStatus, dryRun, postProcessors = savedRepo, savedOut, savedStatus, savedDry, savedChain
+        resetResults()
+    })
+    registerPostProcessor("first", func(path, content string) (string, error) {
+        return strings.ReplaceAll(content, "<b>", "<strong>"), nil
+    })
+    // Sees the first processor's output although nothing was written
+    registerPostProcessor("second", func(path, content string) (string, error) {
+        return strings.ReplaceAll(content, "<strong>", "<em>"), nil
+    })
+
+    file := filepath.Join(dir, "a.component.html")
+    in := "<p><b>a</b></p>\n"
+    if err := os.WriteFile(file, []byte(in), 0644); err != nil {
+        t.Fatal(err)
+    }
+    runPostProcessors(file)
+
+    if got, _ := os.ReadFile(file); string(got) != in {
+        t.Errorf("dry run wrote the file:\n%s", got)
+    }
+    if diff := out.String(); !strings.Contains(diff, "+<p><em>a</b></p>") || strings.Contains(diff, "<strong>") {
+        t.Errorf("diff is not the whole chain's output:\n%s", diff)
+    }
+    if len(results) != 1 || !results[0].changed {
+        t.Errorf("results = %+v, want one changed result", results)
+    }
+    if exitStatus != 1 {
+        t.Errorf("exit status = %d, want 1", exitStatus)
+    }
+}
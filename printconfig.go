@@ -0,0 +1,270 @@
+package main
+
+import (
+    "encoding/json"
+    "fmt"
+    "maps"
+    "os"
+    "path/filepath"
+    "slices"
+    "strings"
+)
+
+// --- PRINT CONFIG ---
+
+// printConfig dumps the effective settings after flags and the config file
+// are merged, then exits (-print-config). Nothing is installed or formatted.
+var printConfig bool
+
+// effectiveConfig is what -print-config reports; it doubles as the JSON shape
+// for -print-config -format json.
+type effectiveConfig struct {
+    Version        string           `json:"version"`
+    Path           string           `json:"path"`
+    ConfigFile     string           `json:"configFile,omitempty"`
+    ToolHome       string           `json:"toolHome"`
+    PackageManager string           `json:"packageManager"`
+    NpmRegistry    string           `json:"npmRegistry,omitempty"`
+    EslintConfig   string           `json:"eslintConfig"`
+    PrettierConfig string           `json:"prettierConfig"`
+    PreferLocal    bool             `json:"preferLocal"`
+    SortImports    bool             `json:"sortImports"`
+    Extensions     []extensionRoute `json:"extensions"`
+    Exclusions     exclusionConfig  `json:"exclusions"`
+    HTML           htmlConfig       `json:"html"`
+    Diff           diffConfig       `json:"diff"`
+}
+
+// extensionRoute is one row of the extension -> handler routing table.
+type extensionRoute struct {
+    Extension string `json:"extension"`
+    Handler   string `json:"handler"`
+    Formatter string `json:"formatter,omitempty"` // the built-in formatter for "native"
+    Source    string `json:"source"`              // "built-in" or "mapped" (config file / -map-ext)
+    Excluded  bool   `json:"excluded,omitempty"`  // -exclude-ext
+}
+
+type exclusionConfig struct {
+    SkipGlobs         []string `json:"skipGlobs"`
+    OnlyGlobs         []string `json:"onlyGlobs"`
+    IgnoreFile        []string `json:"ignoreFile"`        // .go-formatter-ignore
+    AngularIgnoreFile []string `json:"angularIgnoreFile"` // .angularformatignore
+    Generated         []string `json:"generated"`         // empty with -include-generated
+}
+
+type htmlConfig struct {
+    CustomPass    bool   `json:"customPass"`
+    Indent        string `json:"indent"`
+    BraceStyle    string `json:"braceStyle"`
+    MaxBlankLines int    `json:"maxBlankLines"`
+    FinalNewline  bool   `json:"finalNewline"`
+    Validate      bool   `json:"validate"`
+}
+
+type diffConfig struct {
+    Mode    string   `json:"mode"`
+    Current string   `json:"current,omitempty"`
+    Base    string   `json:"base,omitempty"`
+    BaseRef string   `json:"baseRef,omitempty"` // the full ref name the diff would use
+    Args    []string `json:"args,omitempty"`
+    Error   string   `json:"error,omitempty"`
+}
+
+// printEffectiveConfig writes the effective configuration to stdout as text,
+// or as JSON with -format json.
+func printEffectiveConfig(configPath string, opts diffOptions) {
+    cfg := collectConfig(configPath, opts)
+    if outputFormat == "json" {
+        content, err := json.MarshalIndent(cfg, "", "  ")
+        if err != nil {
+            fatalf("Error encoding config: %v", err)
+        }
+        fmt.Println(string(content))
+        return
+    }
+
+    fmt.Printf("go-formatter %s\n", cfg.Version)
+    fmt.Printf("Path:             %s\n", cfg.Path)
+    fmt.Printf("Config file:      %s\n", orNone(cfg.ConfigFile))
+    fmt.Printf("Tool home:        %s\n", cfg.ToolHome)
+    fmt.Printf("Package manager:  %s\n", cfg.PackageManager)
+    fmt.Printf("npm registry:     %s\n", orNone(cfg.NpmRegistry))
+    fmt.Printf("ESLint config:    %s\n", cfg.EslintConfig)
+    fmt.Printf("Prettier config:  %s\n", cfg.PrettierConfig)
+    fmt.Printf("Prefer local:     %t\n", cfg.PreferLocal)
+    fmt.Printf("Sort imports:     %t\n", cfg.SortImports)
+
+    fmt.Println("\nExtensions:")
+    for _, r := range cfg.Extensions {
+        handler := r.Handler
+        if r.Formatter != "" {
+            handler += " (" + r.Formatter + ")"
+        }
+        note := ""
+        if r.Source != "built-in" {
+            note = " [" + r.Source + "]"
+        }
+        if r.Excluded {
+            note += " [excluded]"
+        }
+        fmt.Printf("  %-9s %s%s\n", r.Extension, handler, note)
+    }
+
+    fmt.Println("\nExclusions:")
+    fmt.Printf("  -skip-glob:            %s\n", joinOrNone(cfg.Exclusions.SkipGlobs))
+    fmt.Printf("  -only:                 %s\n", joinOrNone(cfg.Exclusions.OnlyGlobs))
+    fmt.Printf("  %-22s %s\n", ignoreFileName+":", joinOrNone(cfg.Exclusions.IgnoreFile))
+    fmt.Printf("  %-22s %s\n", angularIgnoreFileName+":", joinOrNone(cfg.Exclusions.AngularIgnoreFile))
+    fmt.Printf("  generated:             %s\n", joinOrNone(cfg.Exclusions.Generated))
+
+    fmt.Println("\nHTML:")
+    fmt.Printf("  custom pass:     %t\n", cfg.HTML.CustomPass)
+    fmt.Printf("  indent:          %s\n", cfg.HTML.Indent)
+    fmt.Printf("  brace style:     %s\n", cfg.HTML.BraceStyle)
+    fmt.Printf("  max blank lines: %d\n", cfg.HTML.MaxBlankLines)
+    fmt.Printf("  final newline:   %t\n", cfg.HTML.FinalNewline)
+    fmt.Printf("  validate:        %t\n", cfg.HTML.Validate)
+
+    fmt.Println("\nDiff:")
+    fmt.Printf("  mode:  %s\n", cfg.Diff.Mode)
+    if cfg.Diff.Error != "" {
+        fmt.Printf("  base:  %s\n", cfg.Diff.Error)
+    } else if cfg.Diff.Base != "" {
+        fmt.Printf("  base:  %s (%s)\n", cfg.Diff.Base, cfg.Diff.BaseRef)
+        fmt.Printf("  range: %s...%s\n", cfg.Diff.BaseRef, cfg.Diff.Current)
+    }
+    if len(cfg.Diff.Args) > 0 {
+        fmt.Printf("  args:  %s\n", strings.Join(cfg.Diff.Args, " "))
+    }
+}
+
+func collectConfig(configPath string, opts diffOptions) effectiveConfig {
+    cfg := effectiveConfig{
+        Version:        buildVersion(),
+        Path:           repoPath,
+        ConfigFile:     configPath,
+        ToolHome:       toolHome,
+        PackageManager: packageManager,
+        NpmRegistry:    npmRegistry,
+        EslintConfig:   effectiveToolConfig(eslintConfig, "eslint.config.mjs"),
+        PrettierConfig: effectiveToolConfig(prettierConfig, ".prettierrc"),
+        PreferLocal:    preferLocal,
+        SortImports:    sortImports,
+        Extensions:     routingTable(),
+    }
+
+    cfg.Exclusions = exclusionConfig{
+        SkipGlobs:         nonNil(skipGlobs),
+        OnlyGlobs:         nonNil(onlyGlobs),
+        IgnoreFile:        ignorePatterns(filepath.Join(repoPath, ignoreFileName)),
+        AngularIgnoreFile: ignorePatterns(filepath.Join(repoPath, angularIgnoreFileName)),
+        Generated:         []string{},
+    }
+    if !includeGenerated {
+        cfg.Exclusions.Generated = slices.Clone(generatedPrefixes)
+    }
+
+    cfg.HTML = htmlConfig{
+        CustomPass:    !noCustomHtml,
+        Indent:        describeIndent(indentUnit),
+        BraceStyle:    braceStyle,
+        MaxBlankLines: maxBlankLines,
+        FinalNewline:  finalNewline,
+        Validate:      validateHtml,
+    }
+
+    cfg.Diff = diffConfig{Mode: "parent branch", Args: opts.extra}
+    if modes := opts.modes(); len(modes) > 0 {
+        cfg.Diff.Mode = modes[0]
+        return cfg
+    }
+    if getCommandOutput("git", "rev-parse", "--is-inside-work-tree") != "true" {
+        cfg.Diff.Error = "not a git work tree"
+        return cfg
+    }
+    // Never fetch here: -print-config only reports
+    cfg.Diff.Current, cfg.Diff.Base = detectParentBranch(opts)
+    cfg.Diff.BaseRef = resolveParentRef(cfg.Diff.Base, false)
+    return cfg
+}
+
+// routingTable lists every extension a run would route, sorted, with the
+// handler toolFor picks for it.
+func routingTable() []extensionRoute {
+    exts := make(map[string]bool)
+    for _, m := range []map[string]string{extensionTools, extensionRoutes} {
+        for ext := range m {
+            exts[ext] = true
+        }
+    }
+    for ext := range formatters {
+        exts[ext] = true
+    }
+
+    var routes []extensionRoute
+    for _, ext := range slices.Sorted(maps.Keys(exts)) {
+        route := extensionRoute{Extension: ext, Handler: toolFor(ext), Source: "built-in", Excluded: excludedExts[ext]}
+        if _, ok := extensionRoutes[ext]; ok {
+            route.Source = "mapped"
+        }
+        if route.Handler == "native" {
+            route.Formatter = formatters[ext].name
+        }
+        routes = append(routes, route)
+    }
+    return routes
+}
+
+// effectiveToolConfig names the config file a tool would be given.
+func effectiveToolConfig(override, embeddedName string) string {
+    if override != "" {
+        return resolveRepoPath(override)
+    }
+    return filepath.Join(toolHome, embeddedName)
+}
+
+// ignorePatterns returns the rules of an ignore file as written, without
+// comments and blank lines. A missing file yields an empty list.
+func ignorePatterns(path string) []string {
+    patterns := []string{}
+    content, err := os.ReadFile(path)
+    if err != nil {
+        return patterns
+    }
+    for _, line := range strings.Split(string(content), "\n") {
+        line = strings.TrimRight(line, " \t\r")
+        if line != "" && !strings.HasPrefix(line, "#") {
+            patterns = append(patterns, line)
+        }
+    }
+    return patterns
+}
+
+// describeIndent turns indentUnit back into the -indent value that produced it.
+func describeIndent(indent string) string {
+    if indent == "\t" {
+        return "tab"
+    }
+    return fmt.Sprintf("%d spaces", len(indent))
+}
+
+func nonNil(list []string) []string {
+    if list == nil {
+        return []string{}
+    }
+    return list
+}
+
+func orNone(s string) string {
+    if s == "" {
+        return "(none)"
+    }
+    return s
+}
+
+func joinOrNone(list []string) string {
+    if len(list) == 0 {
+        return "(none)"
+    }
+    return strings.Join(list, ", ")
+}
@@ -0,0 +1,27 @@
+//go:build !windows
+
+package main
+
+import (
+    "io/fs"
+    "os"
+    "os/exec"
+    "syscall"
+)
+
+// killProcessGroupOnCancel starts cmd in its own process group and makes the
+// context kill the whole group, so node workers spawned by npm or ESLint don't
+// outlive a timeout.
+func killProcessGroupOnCancel(cmd *exec.Cmd) {
+    cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+    cmd.Cancel = func() error {
+        return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+    }
+}
+
+// ownedByCurrentUser reports whether info describes a file of the user running
+// the tool.
+func ownedByCurrentUser(info fs.FileInfo) bool {
+    stat, ok := info.Sys().(*syscall.Stat_t)
+    return ok && int(stat.Uid) == os.Getuid()
+}
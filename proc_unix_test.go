@@ -0,0 +1,52 @@
+//go:build !windows
+
+package main
+
+import (
+    "context"
+    "os"
+    "path/filepath"
+    "testing"
+    "time"
+)
+
+// TestCancelRunKillsProcessGroup checks what the interrupt handler relies on:
+// cancelling runCtx kills a running command and the processes it started.
+func TestCancelRunKillsProcessGroup(t *testing.T) {
+    savedCtx, savedCancel := runCtx, cancelRun
+    runCtx, cancelRun = context.WithCancel(context.Background())
+    t.Cleanup(func() { runCtx, cancelRun = savedCtx, savedCancel })
+
+    dir := t.TempDir()
+    started, late := filepath.Join(dir, "started"), filepath.Join(dir, "late")
+    // The child writes a file a second from now, as a tool left running would
+    cmd := newTimedCmd(0, "sh", "-c", "(sleep 1; touch "+late+") & touch "+started+"; wait")
+    done := make(chan error, 1)
+    go func() { done <- cmd.Run() }()
+
+    for deadline := time.Now().Add(5 * time.Second); ; time.Sleep(20 * time.Millisecond) {
+        if _, err := os.Stat(started); err == nil {
+            break
+        }
+        if time.Now().After(deadline) {
+            t.Fatal("the command never started its child")
+        }
+    }
+    if liveCmds.Load() == 0 {
+        t.Error("a running command is not counted as live")
+    }
+
+    cancelRun()
+    select {
+    case <-done:
+    case <-time.After(killGrace):
+        t.Fatal("the command outlived cancelRun")
+    }
+    if liveCmds.Load() != 0 {
+        t.Errorf("%d command(s) still counted as live", liveCmds.Load())
+    }
+    time.Sleep(1500 * time.Millisecond)
+    if _, err := os.Stat(late); err == nil {
+        t.Error("the command's child kept running after the group was killed")
+    }
+}
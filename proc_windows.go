@@ -0,0 +1,28 @@
+//go:build windows
+
+package main
+
+import (
+    "io/fs"
+    "os/exec"
+    "strconv"
+)
+
+// killProcessGroupOnCancel makes the context kill cmd and every process it
+// started. Windows has no process groups to signal, so taskkill /T walks the
+// process tree instead; if that fails, only cmd itself is killed.
+func killProcessGroupOnCancel(cmd *exec.Cmd) {
+    cmd.Cancel = func() error {
+        if err := exec.Command("taskkill", "/T", "/F", "/PID", strconv.Itoa(cmd.Process.Pid)).Run(); err != nil {
+            return cmd.Process.Kill()
+        }
+        return nil
+    }
+}
+
+// ownedByCurrentUser reports whether info describes a file of the user running
+// the tool. Windows has no owner in FileInfo; the per-user profile directories
+// the backups live in are private by their ACLs.
+func ownedByCurrentUser(info fs.FileInfo) bool {
+    return true
+}
@@ -0,0 +1,344 @@
+package main
+
+import (
+    "encoding/json"
+    "errors"
+    "fmt"
+    "io"
+    "io/fs"
+    "os"
+    "path/filepath"
+    "sort"
+    "sync"
+    "time"
+)
+
+// --- RESULTS & REPORTING ---
+
+// fileResult is the outcome of running one tool over one file.
+type fileResult struct {
+    path     string
+    tool     string
+    changed  bool
+    errors   int           // lint errors remaining after the run
+    warnings int           // lint warnings remaining; counted with -max-warnings, a structured report, or when errors remain
+    err      error         // the tool failed on this file
+    skipped  bool          // the file was deleted after the diff was taken
+    elapsed  time.Duration // time spent on this file alone; 0 for batched tools
+    messages []lintMessage // ESLint's remaining problems, for the structured reports
+}
+
+func (r fileResult) failed() bool {
+    return r.errors > 0 || r.err != nil
+}
+
+// onlyErrors limits the final report to failed results.
+var onlyErrors bool
+
+var (
+    resultsMu sync.Mutex
+    results   []fileResult
+)
+
+func recordResult(r fileResult) {
+    haltIfInterrupted()
+    resultsMu.Lock()
+    defer resultsMu.Unlock()
+    results = append(results, r)
+}
+
+// resetResults clears recorded results between watch-mode passes.
+func resetResults() {
+    resultsMu.Lock()
+    defer resultsMu.Unlock()
+    results = nil
+    summary = runSummary{}
+}
+
+// changedPaths returns every path some tool changed (or would change in dry-run).
+func changedPaths() map[string]bool {
+    resultsMu.Lock()
+    defer resultsMu.Unlock()
+    changed := make(map[string]bool)
+    for _, r := range results {
+        if r.changed {
+            changed[r.path] = true
+        }
+    }
+    return changed
+}
+
+// outputFormat selects how results are printed at the end: "text" or "json".
+var outputFormat string
+
+// printResults prints the final report in the selected -format.
+func printResults() {
+    if outputFormat == "json" {
+        printJSONReport()
+        return
+    }
+    printReport()
+    printSummary()
+}
+
+// reportedResults returns the results to show, sorted by path, honoring -only-errors.
+func reportedResults() []fileResult {
+    var shown []fileResult
+    for _, r := range results {
+        if onlyErrors && !r.failed() {
+            continue
+        }
+        shown = append(shown, r)
+    }
+    // Workers finish in any order; keep the report stable
+    sort.SliceStable(shown, func(i, j int) bool { return shown[i].path < shown[j].path })
+    return shown
+}
+
+// printReport lists every recorded result, or only the failures with -only-errors.
+// With -quiet, only failures and files that would change are listed.
+func printReport() {
+    var shown []fileResult
+    for _, r := range reportedResults() {
+        if quiet && !r.failed() && !(dryRun && r.changed) {
+            continue
+        }
+        shown = append(shown, r)
+    }
+    if len(shown) == 0 {
+        return
+    }
+
+    warnf("\nReport:\n")
+    for _, r := range shown {
+        var status string
+        switch {
+        case r.skipped:
+            status = yellow("skipped (deleted during run)")
+        case r.err != nil:
+            status = red(fmt.Sprintf("failed (%v)", r.err))
+        case r.errors > 0:
+            status = red(fmt.Sprintf("%d error(s) remaining", r.errors))
+        case r.changed && dryRun:
+            status = yellow("would change")
+        case r.changed:
+            status = yellow("changed")
+        case r.warnings > 0:
+            status = yellow(fmt.Sprintf("ok, %d warning(s)", r.warnings))
+        default:
+            status = green("ok")
+        }
+        warnf("  %-9s %s: %s\n", r.tool, relPath(r.path), status)
+    }
+}
+
+// --- RUN SUMMARY ---
+
+// phaseStat is how long one processing phase took and how many files it covered.
+type phaseStat struct {
+    name    string
+    files   int
+    elapsed time.Duration
+}
+
+// runSummary is the end-of-run tally printed by printSummary.
+type runSummary struct {
+    linted  int // JS/TS files handed to ESLint
+    html    int // HTML files run through Prettier + the custom pass
+    other   int // stylesheets, JSON/YAML and built-in formatter files
+    changed int // files whose content changed (or would change with -dry-run)
+    phases  []phaseStat
+
+    // Which HTML step changed a file: only Prettier, only the custom pass,
+    // or both (e.g. the custom pass reshaping what Prettier just wrote)
+    htmlPrettierOnly, htmlCustomOnly, htmlBoth int
+}
+
+var summary runSummary
+
+// timePhase runs one processing phase and records its duration.
+func timePhase(name string, files int, run func()) {
+    start := time.Now()
+    run()
+    summary.phases = append(summary.phases, phaseStat{name: name, files: files, elapsed: time.Since(start)})
+}
+
+// printSummary prints the file counts and per-phase timings for the run.
+func printSummary() {
+    changedLabel := "Files changed:"
+    if dryRun {
+        changedLabel = "Would change:"
+    }
+    logln("\nSummary:")
+    logf("  %-16s %d\n", "JS/TS linted:", summary.linted)
+    logf("  %-16s %d\n", "HTML formatted:", summary.html)
+    if summary.other > 0 {
+        logf("  %-16s %d\n", "Other formatted:", summary.other)
+    }
+    if summary.htmlPrettierOnly+summary.htmlCustomOnly+summary.htmlBoth > 0 {
+        logf("  %-16s Prettier only %d, custom pass only %d, both %d\n", "HTML changed by:",
+            summary.htmlPrettierOnly, summary.htmlCustomOnly, summary.htmlBoth)
+    }
+    changed := fmt.Sprint(summary.changed)
+    if summary.changed > 0 {
+        changed = yellow(changed)
+    }
+    logf("  %-16s %s\n", changedLabel, changed)
+
+    var total time.Duration
+    for _, p := range summary.phases {
+        logf("  %-16s %d file(s) in %s\n", p.name+":", p.files, p.elapsed.Round(time.Millisecond))
+        total += p.elapsed
+    }
+    logf("  %-16s %s\n", "Total time:", total.Round(time.Millisecond))
+}
+
+// tallyHtmlSteps records, for each HTML file, whether Prettier changed it and
+// whether the custom pass changed it further, so churn from the two steps
+// disagreeing can be told apart from a plain reformat (-verbose lists files).
+func tallyHtmlSteps(files []string) {
+    byPrettier := changedBy("prettier")
+    byCustom := changedBy(htmlFormatter().name)
+    for _, f := range files {
+        switch p, c := byPrettier[f], byCustom[f]; {
+        case p && c:
+            summary.htmlBoth++
+            verbosef("%s: changed by Prettier and the custom pass", relPath(f))
+        case p:
+            summary.htmlPrettierOnly++
+            verbosef("%s: changed by Prettier only", relPath(f))
+        case c:
+            summary.htmlCustomOnly++
+            verbosef("%s: changed by the custom pass only", relPath(f))
+        }
+    }
+}
+
+// changedBy returns the paths the named tool changed (or would change).
+func changedBy(tool string) map[string]bool {
+    resultsMu.Lock()
+    defer resultsMu.Unlock()
+    changed := make(map[string]bool)
+    for _, r := range results {
+        if r.tool == tool && r.changed {
+            changed[r.path] = true
+        }
+    }
+    return changed
+}
+
+// --- JSON REPORT ---
+
+type jsonFileResult struct {
+    Path     string `json:"path"`
+    Tool     string `json:"tool"`
+    Changed  bool   `json:"changed"`
+    Errors   int    `json:"errors"`
+    Warnings int    `json:"warnings,omitempty"`
+    Error    string `json:"error,omitempty"`
+    Skipped  bool   `json:"skipped,omitempty"`
+
+    Messages []jsonLintMessage `json:"messages,omitempty"`
+}
+
+type jsonLintMessage struct {
+    RuleID   string `json:"ruleId,omitempty"`
+    Severity string `json:"severity"`
+    Message  string `json:"message"`
+    Line     int    `json:"line,omitempty"`
+    Column   int    `json:"column,omitempty"`
+}
+
+type jsonPhase struct {
+    Name       string `json:"name"`
+    Files      int    `json:"files"`
+    DurationMs int64  `json:"durationMs"`
+}
+
+type jsonReport struct {
+    DryRun   bool             `json:"dryRun"`
+    ExitCode int              `json:"exitCode"`
+    Files    []jsonFileResult `json:"files"`
+    Summary  struct {
+        Linted  int         `json:"linted"`
+        HTML    int         `json:"html"`
+        Other   int         `json:"other"`
+        Changed int         `json:"changed"`
+        Phases  []jsonPhase `json:"phases"`
+
+        HTMLChangedBy struct {
+            PrettierOnly   int `json:"prettierOnly"`
+            CustomPassOnly int `json:"customPassOnly"`
+            Both           int `json:"both"`
+        } `json:"htmlChangedBy"`
+    } `json:"summary"`
+}
+
+// printJSONReport writes the results as a single JSON object to stdout.
+// Progress messages have already gone to stderr (see logOut).
+func printJSONReport() {
+    report := jsonReport{DryRun: dryRun, Files: []jsonFileResult{}}
+    exitMu.Lock()
+    report.ExitCode = exitStatus
+    exitMu.Unlock()
+
+    for _, r := range reportedResults() {
+        entry := jsonFileResult{Path: relPath(r.path), Tool: r.tool, Changed: r.changed, Errors: r.errors, Warnings: r.warnings, Skipped: r.skipped}
+        if r.err != nil {
+            entry.Error = r.err.Error()
+        }
+        for _, m := range r.messages {
+            entry.Messages = append(entry.Messages, jsonLintMessage{RuleID: m.ruleID, Severity: severityName(m.severity), Message: m.message, Line: m.line, Column: m.column})
+        }
+        report.Files = append(report.Files, entry)
+    }
+    report.Summary.Linted = summary.linted
+    report.Summary.HTML = summary.html
+    report.Summary.Other = summary.other
+    report.Summary.Changed = summary.changed
+    report.Summary.HTMLChangedBy.PrettierOnly = summary.htmlPrettierOnly
+    report.Summary.HTMLChangedBy.CustomPassOnly = summary.htmlCustomOnly
+    report.Summary.HTMLChangedBy.Both = summary.htmlBoth
+    report.Summary.Phases = []jsonPhase{}
+    for _, p := range summary.phases {
+        report.Summary.Phases = append(report.Summary.Phases, jsonPhase{Name: p.name, Files: p.files, DurationMs: p.elapsed.Milliseconds()})
+    }
+
+    content, err := json.MarshalIndent(report, "", "  ")
+    if err != nil {
+        warnf("Error encoding JSON report: %v\n", err)
+        return
+    }
+    fmt.Println(string(content))
+}
+
+// deletedDuringRun reports whether file is gone now, typically removed by a
+// branch switch or build step after the diff was taken.
+func deletedDuringRun(file string) bool {
+    _, err := os.Stat(file)
+    return errors.Is(err, fs.ErrNotExist)
+}
+
+// dropDeleted returns the files that still exist and records the others as
+// skipped for tool, so one vanished file doesn't fail a whole batch.
+func dropDeleted(files []string, tool string, out io.Writer) []string {
+    var kept []string
+    for _, f := range files {
+        if deletedDuringRun(f) {
+            fmt.Fprintf(out, "Skipped %s (deleted during run).\n", relPath(f))
+            recordResult(fileResult{path: f, tool: tool, skipped: true})
+            continue
+        }
+        kept = append(kept, f)
+    }
+    return kept
+}
+
+// relPath shows a path relative to the repo with forward slashes.
+func relPath(path string) string {
+    rel, err := filepath.Rel(repoPath, path)
+    if err != nil {
+        return path
+    }
+    return filepath.ToSlash(rel)
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+    "encoding/json"
+    "os"
+    "path/filepath"
+    "sort"
+)
+
+// --- SARIF REPORT ---
+
+// sarifPath is where -sarif writes a SARIF 2.1.0 report ("" writes none).
+// Setting it makes ESLint's check pass run for every chunk, so the report
+// has each remaining message and not just the files that failed.
+var sarifPath string
+
+// sarifFormatRule is reported for files that need formatting in a dry run.
+const sarifFormatRule = "go-formatter/format"
+
+type sarifLog struct {
+    Schema  string     `json:"$schema"`
+    Version string     `json:"version"`
+    Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+    Tool    sarifTool     `json:"tool"`
+    Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+    Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+    Name           string      `json:"name"`
+    Version        string      `json:"version,omitempty"`
+    InformationURI string      `json:"informationUri,omitempty"`
+    Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+    ID string `json:"id"`
+}
+
+type sarifResult struct {
+    RuleID    string          `json:"ruleId,omitempty"`
+    Level     string          `json:"level"`
+    Message   sarifText       `json:"message"`
+    Locations []sarifLocation `json:"locations"`
+}
+
+type sarifText struct {
+    Text string `json:"text"`
+}
+
+type sarifLocation struct {
+    PhysicalLocation struct {
+        ArtifactLocation struct {
+            URI       string `json:"uri"`
+            URIBaseID string `json:"uriBaseId"`
+        } `json:"artifactLocation"`
+        Region *sarifRegion `json:"region,omitempty"`
+    } `json:"physicalLocation"`
+}
+
+type sarifRegion struct {
+    StartLine   int `json:"startLine"`
+    StartColumn int `json:"startColumn,omitempty"`
+    EndLine     int `json:"endLine,omitempty"`
+    EndColumn   int `json:"endColumn,omitempty"`
+}
+
+// writeSarifReport converts every remaining ESLint message, and in a dry run
+// every file that needs formatting, into a SARIF result. Paths are relative
+// to the git top-level, which is what code scanning expects.
+func writeSarifReport(path string) {
+    resultsMu.Lock()
+    all := append([]fileResult(nil), results...)
+    resultsMu.Unlock()
+    sort.SliceStable(all, func(i, j int) bool { return all[i].path < all[j].path })
+
+    run := sarifRun{
+        Tool: sarifTool{Driver: sarifDriver{
+            Name:           "go-formatter",
+            Version:        buildVersion(),
+            InformationURI: "https://github.com/caseycole589/go-formatter",
+        }},
+        Results: []sarifResult{},
+    }
+    rules := make(map[string]bool)
+
+    for _, r := range all {
+        uri := sarifURI(r.path)
+        for _, m := range r.messages {
+            result := sarifResult{RuleID: m.ruleID, Level: severityName(m.severity), Message: sarifText{m.message}}
+            loc := sarifLocation{}
+            loc.PhysicalLocation.ArtifactLocation.URI = uri
+            loc.PhysicalLocation.ArtifactLocation.URIBaseID = "%SRCROOT%"
+            if m.line > 0 {
+                loc.PhysicalLocation.Region = &sarifRegion{StartLine: m.line, StartColumn: m.column, EndLine: m.endLine, EndColumn: m.endColumn}
+            }
+            result.Locations = []sarifLocation{loc}
+            run.Results = append(run.Results, result)
+            if m.ruleID != "" {
+                rules[m.ruleID] = true
+            }
+        }
+        if r.changed && dryRun {
+            result := sarifResult{RuleID: sarifFormatRule, Level: "warning", Message: sarifText{"File is not formatted (" + r.tool + ")."}}
+            loc := sarifLocation{}
+            loc.PhysicalLocation.ArtifactLocation.URI = uri
+            loc.PhysicalLocation.ArtifactLocation.URIBaseID = "%SRCROOT%"
+            result.Locations = []sarifLocation{loc}
+            run.Results = append(run.Results, result)
+            rules[sarifFormatRule] = true
+        }
+    }
+
+    run.Tool.Driver.Rules = []sarifRule{}
+    for id := range rules {
+        run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, sarifRule{ID: id})
+    }
+    sort.Slice(run.Tool.Driver.Rules, func(i, j int) bool { return run.Tool.Driver.Rules[i].ID < run.Tool.Driver.Rules[j].ID })
+
+    report := sarifLog{
+        Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+        Version: "2.1.0",
+        Runs:    []sarifRun{run},
+    }
+    content, err := json.MarshalIndent(report, "", "  ")
+    if err != nil {
+        warnf("Error encoding SARIF report: %v\n", err)
+        setExitStatus(2)
+        return
+    }
+    if err := os.WriteFile(path, append(content, '\n'), 0644); err != nil {
+        warnf("Error writing SARIF report %s: %v\n", path, err)
+        setExitStatus(2)
+        return
+    }
+    logf("SARIF report written to %s (%d result(s))\n", path, len(run.Results))
+}
+
+// sarifURI makes path relative to the git top-level, falling back to -path
+// when git was not used (explicit files or -stdin).
+func sarifURI(path string) string {
+    if gitRoot != "" {
+        if prefix, err := filepath.Rel(resolveSymlinks(gitRoot), resolveSymlinks(repoPath)); err == nil {
+            return filepath.ToSlash(filepath.Join(prefix, filepath.FromSlash(relPath(path))))
+        }
+    }
+    return relPath(path)
+}
@@ -0,0 +1,248 @@
+package main
+
+import (
+    "bufio"
+    "bytes"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "net"
+    "os"
+    "os/exec"
+    "os/signal"
+    "path/filepath"
+    "strings"
+    "sync"
+)
+
+// --- SERVER MODE ---
+
+// serveAddr starts a formatting server instead of a one-shot run (-serve).
+// "unix:/path/to.sock" listens on a unix socket, anything else is a TCP
+// address that must be on the loopback interface.
+var serveAddr string
+
+// serveMaxRequest caps one request line; large enough for any source file.
+const serveMaxRequest = 64 << 20
+
+// serveRequest is one line of JSON sent by the editor. Content is optional;
+// without it the file is read from disk. The file is never written.
+type serveRequest struct {
+    Path    string  `json:"path"`
+    Content *string `json:"content,omitempty"`
+}
+
+// serveResponse is the single JSON line written back for each request.
+type serveResponse struct {
+    Path    string `json:"path"`
+    Content string `json:"content"`
+    Changed bool   `json:"changed"`
+    Tool    string `json:"tool,omitempty"`
+    Errors  int    `json:"errors,omitempty"` // ESLint errors left after fixing
+    Ignored bool   `json:"ignored,omitempty"`
+    Error   string `json:"error,omitempty"`
+}
+
+// serve answers formatting requests until interrupted. The tool environment
+// is set up once at startup, so a request only pays for the ESLint/Prettier
+// process it needs rather than for a whole run.
+func serve(addr string) {
+    network, address := "tcp", addr
+    if path, ok := strings.CutPrefix(addr, "unix:"); ok {
+        network, address = "unix", path
+        // A socket left behind by a killed server would block the listen;
+        // anything else at that path is not ours to delete
+        if fi, err := os.Lstat(path); err == nil {
+            if fi.Mode()&os.ModeSocket == 0 {
+                fatalf("-serve: %s exists and is not a socket", path)
+            }
+            os.Remove(path)
+        }
+    } else if !isLoopback(addr) {
+        fatalf("-serve only listens on localhost (e.g. 127.0.0.1:7878 or unix:/tmp/go-formatter.sock), got %q", addr)
+    }
+
+    listener, err := net.Listen(network, address)
+    if err != nil {
+        fatalf("Could not listen on %s: %v", addr, err)
+    }
+    stopExitOnInterrupt()
+    go func() {
+        interrupt := make(chan os.Signal, 1)
+        signal.Notify(interrupt, os.Interrupt)
+        <-interrupt
+        listener.Close()
+    }()
+
+    logf("Serving on %s (one JSON request per line; Ctrl-C to stop)...\n", addr)
+    var wg sync.WaitGroup
+    for {
+        conn, err := listener.Accept()
+        if err != nil {
+            break
+        }
+        wg.Add(1)
+        go func() {
+            defer wg.Done()
+            handleConn(conn)
+        }()
+    }
+    wg.Wait()
+    logln("\nServer stopped.")
+}
+
+// isLoopback reports whether a host:port address resolves to this machine only.
+func isLoopback(addr string) bool {
+    host, _, err := net.SplitHostPort(addr)
+    if err != nil {
+        return false
+    }
+    if host == "localhost" {
+        return true
+    }
+    ip := net.ParseIP(host)
+    return ip != nil && ip.IsLoopback()
+}
+
+// handleConn serves requests from one client, in order, until it disconnects.
+func handleConn(conn net.Conn) {
+    defer conn.Close()
+    scanner := bufio.NewScanner(conn)
+    scanner.Buffer(make([]byte, 0, 64*1024), serveMaxRequest)
+    encoder := json.NewEncoder(conn)
+    encoder.SetEscapeHTML(false)
+    for scanner.Scan() {
+        var req serveRequest
+        var resp serveResponse
+        if err := json.Unmarshal(scanner.Bytes(), &req); err != nil {
+            resp.Error = fmt.Sprintf("invalid request: %v", err)
+        } else {
+            resp = serveFile(req)
+        }
+        if err := encoder.Encode(resp); err != nil {
+            return
+        }
+    }
+}
+
+// serveFile formats one request's content with the same routing as a normal
+// run and returns the result without touching the file on disk.
+func serveFile(req serveRequest) serveResponse {
+    if req.Path == "" {
+        return serveResponse{Error: "missing path"}
+    }
+    file := resolveRepoPath(req.Path)
+    resp := serveResponse{Path: req.Path}
+    // The server has no auth: it must not become a way to read any file the
+    // user can, so nothing outside the repository is opened
+    if rel, err := filepath.Rel(repoPath, file); err != nil || !filepath.IsLocal(rel) {
+        resp.Error = "path is outside the repository"
+        return resp
+    }
+
+    if req.Content != nil {
+        resp.Content = *req.Content
+    } else {
+        content, err := os.ReadFile(file)
+        if err != nil {
+            resp.Error = err.Error()
+            return resp
+        }
+        resp.Content = string(content)
+    }
+    original := resp.Content
+
+    if excludedExts[extOf(file)] || loadIgnoreFile(filepath.Join(repoPath, ignoreFileName)).Match(relPath(file)) {
+        resp.Ignored = true
+        return resp
+    }
+    resp.Tool = toolFor(extOf(file))
+
+    var err error
+    switch resp.Tool {
+    case "":
+        err = fmt.Errorf("unsupported extension %q", extOf(file))
+    case "eslint":
+        resp.Content, resp.Errors, err = eslintContent(file, resp.Content)
+        if ext := extOf(file); err == nil && sortImports && (ext == ".ts" || ext == ".tsx") {
+            resp.Content = sortImportBlock(resp.Content)
+        }
+        if err == nil && extOf(file) == ".ts" && !skipAllman(file, angularOptOut()) {
+            resp.Content, err = formatInlineTemplates(resp.Content)
+        }
+    case "markdown":
+        resp.Content, err = formatMarkdownFences(file, resp.Content)
+    case "native":
+        var out []byte
+        out, err = formatters[extOf(file)].Format([]byte(resp.Content))
+        resp.Content = string(out)
+    default:
+        resp.Content, err = prettierContent(file, resp.Content)
+        if err == nil && resp.Tool == "html" {
+            resp.Content, err = customHtmlContent(file, resp.Content)
+        }
+    }
+    if err != nil {
+        // The editor keeps its buffer; echoing the file back would only
+        // leak content the request never sent
+        resp.Content = ""
+        resp.Error = err.Error()
+        return resp
+    }
+    resp.Changed = resp.Content != original
+    return resp
+}
+
+// prettierContent formats content through Prettier's stdin, using file only
+// to pick the parser and config.
+func prettierContent(file, content string) (string, error) {
+    prettierBin, _ := resolveBin(toolRoot(), "prettier")
+    args := append(configArgs(prettierConfig, ".prettierrc"), "--stdin-filepath", file)
+    if !useLocalTools && extOf(file) == ".svelte" {
+        if plugin, ok := prettierPluginPath("prettier-plugin-svelte"); ok {
+            args = append(args, "--plugin", plugin)
+        }
+    }
+
+    cmd := binCommand(prettierBin, args...)
+    cmd.Dir = repoPath
+    cmd.Stdin = strings.NewReader(content)
+    var stderr bytes.Buffer
+    cmd.Stderr = &stderr
+    logCommand(cmd)
+    out, err := cmd.Output()
+    if err != nil {
+        return "", fmt.Errorf("prettier: %v: %s", err, strings.TrimSpace(stderr.String()))
+    }
+    return string(out), nil
+}
+
+// eslintContent runs ESLint's fixes over content via stdin and returns the
+// fixed text and how many errors remain.
+func eslintContent(file, content string) (string, int, error) {
+    eslintBin, _ := resolveBin(toolRoot(), "eslint")
+    args := append(configArgs(eslintConfig, "eslint.config.mjs"),
+        "--stdin", "--stdin-filename", file, "--fix-dry-run", "--format", "json")
+
+    cmd := binCommand(eslintBin, args...)
+    cmd.Dir = repoPath
+    cmd.Stdin = strings.NewReader(content)
+    var stderr bytes.Buffer
+    cmd.Stderr = &stderr
+    logCommand(cmd)
+    out, err := cmd.Output()
+    // Exit 1 only means errors remain; the JSON still has the fixed output
+    var exitErr *exec.ExitError
+    if err != nil && !(errors.As(err, &exitErr) && exitErr.ExitCode() == 1) {
+        return "", 0, fmt.Errorf("eslint: %v: %s", err, strings.TrimSpace(stderr.String()))
+    }
+
+    reports, err := parseEslintJSON(out)
+    if err != nil || len(reports) == 0 {
+        return "", 0, fmt.Errorf("eslint: unexpected output: %s", strings.TrimSpace(string(out)))
+    }
+    if reports[0].output != nil {
+        content = *reports[0].output
+    }
+    return content, reports[0].counts.errors, nil
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+    "os"
+    "path/filepath"
+    "testing"
+)
+
+func TestServeFileOutsideRepo(t *testing.T) {
+    root := t.TempDir()
+    savedRepo := repoPath
+    repoPath = filepath.Join(root, "repo")
+    t.Cleanup(func() { repoPath = savedRepo })
+    secret := filepath.Join(root, "secret.ts")
+    if err := os.MkdirAll(repoPath, 0755); err != nil {
+        t.Fatal(err)
+    }
+    if err := os.WriteFile(secret, []byte("const key = 'x';\n"), 0600); err != nil {
+        t.Fatal(err)
+    }
+
+    for _, path := range []string{"../secret.ts", secret, "src/../../secret.ts"} {
+        resp := serveFile(serveRequest{Path: path})
+        if resp.Error == "" || resp.Content != "" {
+            t.Errorf("%s: got %+v, want an error and no content", path, resp)
+        }
+    }
+}
+
+func TestServeFileErrorHasNoContent(t *testing.T) {
+    savedRepo := repoPath
+    repoPath = t.TempDir()
+    t.Cleanup(func() { repoPath = savedRepo })
+    write := func(name, content string) {
+        if err := os.WriteFile(filepath.Join(repoPath, name), []byte(content), 0644); err != nil {
+            t.Fatal(err)
+        }
+    }
+    write("notes.unknown", "private notes\n")
+    write("broken.go", "package main\nfunc {\n")
+
+    for _, path := range []string{"notes.unknown", "broken.go"} {
+        resp := serveFile(serveRequest{Path: path})
+        if resp.Error == "" || resp.Content != "" {
+            t.Errorf("%s: got %+v, want an error and no content", path, resp)
+        }
+    }
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "os"
+    "os/exec"
+    "os/signal"
+    "path/filepath"
+    "strings"
+    "sync/atomic"
+    "syscall"
+    "time"
+)
+
+// --- COMMAND TIMEOUTS ---
+
+// toolTimeout bounds each git/ESLint/Prettier invocation (-timeout).
+// installTimeout bounds each dependency install attempt (-install-timeout).
+// Zero disables the limit.
+var (
+    toolTimeout    time.Duration
+    installTimeout time.Duration
+)
+
+// killGrace is how long Wait keeps waiting for output after the process group
+// was killed, in case a detached grandchild still holds the pipes open.
+const killGrace = 5 * time.Second
+
+// timedCmd is an external command that is killed, together with every process
+// it started, once its deadline passes. Run, Output, CombinedOutput and Wait
+// return a *timeoutError in that case.
+type timedCmd struct {
+    *exec.Cmd
+    ctx     context.Context
+    cancel  context.CancelFunc
+    timeout time.Duration
+}
+
+// timeoutError names the command that was killed, so a stuck CI job says what hung.
+type timeoutError struct {
+    command string
+    timeout time.Duration
+}
+
+func (e *timeoutError) Error() string {
+    return fmt.Sprintf("%s timed out after %s", e.command, e.timeout)
+}
+
+// runCtx is the parent of every command's context. Cancelling it kills every
+// live process group at once; see exitOnInterrupt.
+var runCtx, cancelRun = context.WithCancel(context.Background())
+
+// liveCmds counts the commands started and not yet waited for.
+var liveCmds atomic.Int64
+
+func newTimedCmd(timeout time.Duration, name string, args ...string) *timedCmd {
+    ctx, cancel := context.WithCancel(runCtx)
+    if timeout > 0 {
+        ctx, cancel = context.WithTimeout(runCtx, timeout)
+    }
+    cmd := exec.CommandContext(ctx, name, args...)
+    killProcessGroupOnCancel(cmd)
+    cmd.WaitDelay = killGrace
+    return &timedCmd{Cmd: cmd, ctx: ctx, cancel: cancel, timeout: timeout}
+}
+
+func (c *timedCmd) Run() error {
+    liveCmds.Add(1)
+    defer liveCmds.Add(-1)
+    return c.finish(c.Cmd.Run())
+}
+
+func (c *timedCmd) Start() error {
+    if err := c.Cmd.Start(); err != nil {
+        return err
+    }
+    liveCmds.Add(1)
+    return nil
+}
+
+func (c *timedCmd) Wait() error {
+    defer liveCmds.Add(-1)
+    return c.finish(c.Cmd.Wait())
+}
+
+func (c *timedCmd) Output() ([]byte, error) {
+    liveCmds.Add(1)
+    defer liveCmds.Add(-1)
+    out, err := c.Cmd.Output()
+    return out, c.finish(err)
+}
+
+func (c *timedCmd) CombinedOutput() ([]byte, error) {
+    liveCmds.Add(1)
+    defer liveCmds.Add(-1)
+    out, err := c.Cmd.CombinedOutput()
+    return out, c.finish(err)
+}
+
+// finish releases the deadline and turns a kill caused by it into a timeoutError.
+func (c *timedCmd) finish(err error) error {
+    defer c.cancel()
+    if err != nil && errors.Is(c.ctx.Err(), context.DeadlineExceeded) {
+        return &timeoutError{command: c.describe(), timeout: c.timeout}
+    }
+    return err
+}
+
+// describe returns a short name for the command: the program and, if it is
+// a subcommand rather than a flag or path, its first argument ("git diff",
+// "npm install", "eslint").
+func (c *timedCmd) describe() string {
+    args := c.Args
+    // PowerShell shims are reported by the script they run
+    if len(args) > 0 && strings.EqualFold(filepath.Base(args[0]), "powershell") {
+        for i, a := range args {
+            if a == "-File" && i+1 < len(args) {
+                args = args[i+1:]
+                break
+            }
+        }
+    }
+    if len(args) == 0 {
+        return c.Path
+    }
+    name := strings.TrimSuffix(filepath.Base(args[0]), filepath.Ext(args[0]))
+    if len(args) > 1 && !strings.HasPrefix(args[1], "-") && !strings.ContainsAny(args[1], `/\.`) {
+        name += " " + args[1]
+    }
+    return name
+}
+
+// --- INTERRUPTS ---
+
+// interrupts receives Ctrl-C and SIGTERM while exitOnInterrupt is in charge.
+var interrupts = make(chan os.Signal, 1)
+
+// exitOnInterrupt makes Ctrl-C and SIGTERM stop the run cleanly. The tools run
+// in their own process groups, out of reach of the terminal's SIGINT, so
+// without this ESLint, Prettier or npm would keep writing files after
+// go-formatter is gone. The handler kills every live group, waits up to
+// killGrace for them to exit, releases the tool home lock and exits 130,
+// keeping any -backup copies.
+func exitOnInterrupt() {
+    signal.Notify(interrupts, os.Interrupt, syscall.SIGTERM)
+    go func() {
+        if _, ok := <-interrupts; !ok {
+            return
+        }
+        warnf("\n%s\n", red("Interrupted; stopping the running tools..."))
+        cancelRun()
+        deadline := time.Now().Add(killGrace)
+        for liveCmds.Load() > 0 && time.Now().Before(deadline) {
+            time.Sleep(50 * time.Millisecond)
+        }
+        installLock.release()
+        setExitStatus(130)
+        finishBackups()
+        os.Exit(130)
+    }()
+}
+
+// haltIfInterrupted parks the calling goroutine once the run was interrupted.
+// A tool killed by the handler returns an error to its caller right away;
+// this keeps that caller from recording it, writing the next file or exiting
+// with another status before the handler has finished shutting down.
+func haltIfInterrupted() {
+    if runCtx.Err() != nil {
+        select {}
+    }
+}
+
+// stopExitOnInterrupt hands Ctrl-C to -watch and -serve, which stop on it
+// after the file or request in progress instead of exiting at once.
+func stopExitOnInterrupt() {
+    signal.Stop(interrupts)
+    close(interrupts)
+}
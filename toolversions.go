@@ -0,0 +1,136 @@
+package main
+
+import (
+    "encoding/json"
+    "fmt"
+    "os"
+    "path/filepath"
+    "strconv"
+    "strings"
+)
+
+// --- TOOL VERSIONS ---
+
+// embeddedLockName is npm's lockfile. When it is embedded next to
+// package.json, npm installs with "npm ci", so every machine gets exactly
+// the versions it lists.
+const embeddedLockName = "package-lock.json"
+
+// formattingTools are the dependencies whose version changes the output.
+var formattingTools = []string{"eslint", "prettier"}
+
+// embeddedLock returns the embedded lockfile, if this binary has one.
+func embeddedLock() ([]byte, bool) {
+    content, err := configFiles.ReadFile("configs/" + embeddedLockName)
+    return content, err == nil
+}
+
+// expectedToolVersion returns what the embedded configs ask for: the exact
+// version from the lockfile when there is one, else the range from
+// package.json (e.g. "^3.0.0").
+func expectedToolVersion(name string) (version string, exact bool) {
+    if lock, ok := embeddedLock(); ok {
+        var parsed struct {
+            Packages map[string]struct {
+                Version string `json:"version"`
+            } `json:"packages"`
+        }
+        if json.Unmarshal(lock, &parsed) == nil {
+            if pkg, ok := parsed.Packages["node_modules/"+name]; ok {
+                return pkg.Version, true
+            }
+        }
+    }
+    content, _ := configFiles.ReadFile("configs/package.json")
+    var pkg struct {
+        Dependencies map[string]string `json:"dependencies"`
+    }
+    json.Unmarshal(content, &pkg)
+    return pkg.Dependencies[name], false
+}
+
+// installedToolVersion reads the version of a package installed in toolHome.
+func installedToolVersion(name string) string {
+    content, err := os.ReadFile(filepath.Join(toolHome, "node_modules", name, "package.json"))
+    if err != nil {
+        return ""
+    }
+    var pkg struct {
+        Version string `json:"version"`
+    }
+    json.Unmarshal(content, &pkg)
+    return pkg.Version
+}
+
+// checkToolVersions warns when an installed ESLint or Prettier is not the
+// version the embedded configs expect, since a different version may format
+// differently than on other machines.
+func checkToolVersions() {
+    for _, name := range formattingTools {
+        installed := installedToolVersion(name)
+        expected, exact := expectedToolVersion(name)
+        if installed == "" || expected == "" {
+            continue
+        }
+        if exact && installed != expected || !exact && !satisfiesRange(installed, expected) {
+            warnf("%s\n", yellow(fmt.Sprintf("Warning: %s %s is installed in %s, but %s is expected; formatting may differ from other machines. Delete %s to reinstall.",
+                name, installed, toolHome, expected, filepath.Join(toolHome, "node_modules"))))
+        }
+    }
+}
+
+// satisfiesRange handles the range forms used in the embedded package.json:
+// "^1.2.3" (same major, at least 1.2.3), "~1.2.3" (same minor) and "1.2.3".
+// Other forms are assumed to match.
+func satisfiesRange(version, rng string) bool {
+    have, ok := parseSemver(version)
+    if !ok {
+        return true
+    }
+    op := rng[:1]
+    if op == "^" || op == "~" {
+        rng = rng[1:]
+    } else {
+        op = ""
+    }
+    want, ok := parseSemver(rng)
+    if !ok {
+        return true
+    }
+    switch op {
+    case "^":
+        return have[0] == want[0] && !semverLess(have, want)
+    case "~":
+        return have[0] == want[0] && have[1] == want[1] && !semverLess(have, want)
+    default:
+        return have == want
+    }
+}
+
+// parseSemver parses "major.minor.patch", ignoring any prerelease or build suffix.
+func parseSemver(v string) ([3]int, bool) {
+    var out [3]int
+    v, _, _ = strings.Cut(v, "-")
+    v, _, _ = strings.Cut(v, "+")
+    parts := strings.Split(v, ".")
+    if len(parts) != 3 {
+        return out, false
+    }
+    for i, p := range parts {
+        n, err := strconv.Atoi(p)
+        if err != nil {
+            return out, false
+        }
+        out[i] = n
+    }
+    return out, true
+}
+
+func semverLess(a, b [3]int) bool {
+    for i := range a {
+        if a[i] != b[i] {
+            return a[i] < b[i]
+        }
+    }
+    return false
+}
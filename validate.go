@@ -0,0 +1,83 @@
+package main
+
+import (
+    "fmt"
+    "strings"
+
+    "golang.org/x/net/html"
+)
+
+// --- HTML VALIDATION ---
+
+// validateHtml checks that the custom HTML pass kept every template's tag
+// structure (-validate-html). A mismatch leaves the file unchanged.
+var validateHtml bool
+
+// validatingFormatter wraps the Allman pass and rejects output whose tags
+// differ from the input's.
+type validatingFormatter struct {
+    Formatter
+}
+
+func (v validatingFormatter) Format(src []byte) ([]byte, error) {
+    out, err := v.Formatter.Format(src)
+    if err != nil {
+        return out, err
+    }
+    if err := compareTagStructure(string(src), string(out)); err != nil {
+        return nil, err
+    }
+    return out, nil
+}
+
+// htmlFormatter returns the custom HTML pass, validated with -validate-html.
+func htmlFormatter() namedFormatter {
+    f := formatters[".html"]
+    if validateHtml {
+        f.Formatter = validatingFormatter{f.Formatter}
+    }
+    return f
+}
+
+// tagStructure lists the start, end and self-closing tags of content in
+// order, each with its attribute names: "<div class [hidden]>", "</div>".
+// The tokenizer is lenient, so Angular syntax such as (click), *ngIf, @if
+// blocks or {{ }} never fails to parse; it is simply text or an attribute.
+// Attribute values and text are left out, since whitespace in them may
+// legitimately change.
+func tagStructure(content string) []string {
+    var tags []string
+    z := html.NewTokenizer(strings.NewReader(content))
+    for {
+        switch z.Next() {
+        case html.ErrorToken:
+            return tags
+        case html.StartTagToken, html.SelfClosingTagToken:
+            t := z.Token()
+            sig := "<" + t.Data
+            for _, a := range t.Attr {
+                sig += " " + a.Key
+            }
+            tags = append(tags, sig+">")
+        case html.EndTagToken:
+            tags = append(tags, "</"+z.Token().Data+">")
+        }
+    }
+}
+
+// compareTagStructure reports the first tag that was lost, added or changed
+// between before and after.
+func compareTagStructure(before, after string) error {
+    a, b := tagStructure(before), tagStructure(after)
+    for i := 0; i < len(a) || i < len(b); i++ {
+        switch {
+        case i >= len(b):
+            return fmt.Errorf("-validate-html: output lost %s (tag %d of %d)", a[i], i+1, len(a))
+        case i >= len(a):
+            return fmt.Errorf("-validate-html: output added %s (tag %d)", b[i], i+1)
+        case a[i] != b[i]:
+            return fmt.Errorf("-validate-html: tag %d changed from %s to %s", i+1, a[i], b[i])
+        }
+    }
+    return nil
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+    "crypto/sha256"
+    "encoding/hex"
+    "fmt"
+    "runtime"
+    "runtime/debug"
+)
+
+// --- VERSION ---
+
+// version is stamped by release builds:
+//
+//    go build -ldflags "-X main.version=v1.4.0"
+//
+// Without it, the module version from `go install ...@version` is used.
+var version = "dev"
+
+// showVersion prints the build metadata and exits (-version).
+var showVersion bool
+
+func printVersion() {
+    fmt.Printf("go-formatter %s\n", buildVersion())
+    fmt.Printf("  go:      %s %s/%s\n", runtime.Version(), runtime.GOOS, runtime.GOARCH)
+    fmt.Printf("  configs: %s\n", embeddedConfigHash()[:12])
+}
+
+// buildVersion is the stamped version, else the module version for an
+// `go install`ed binary, else "dev".
+func buildVersion() string {
+    if info, ok := debug.ReadBuildInfo(); ok && version == "dev" && info.Main.Version != "" && info.Main.Version != "(devel)" {
+        return info.Main.Version
+    }
+    return version
+}
+
+// embeddedConfigHash fingerprints the configs compiled into this binary, so
+// two installs can be compared even when both report the same version.
+func embeddedConfigHash() string {
+    h := sha256.New()
+    hashEmbeddedConfigs(h)
+    return hex.EncodeToString(h.Sum(nil))
+}
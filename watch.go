@@ -0,0 +1,127 @@
+package main
+
+import (
+    "context"
+    "io/fs"
+    "os"
+    "os/signal"
+    "path/filepath"
+    "time"
+
+    "github.com/fsnotify/fsnotify"
+)
+
+// --- WATCH MODE ---
+
+// watch keeps running after the first pass and re-formats files as they are saved.
+var watch bool
+
+// watchDebounce coalesces the burst of events editors emit for a single save.
+const watchDebounce = 300 * time.Millisecond
+
+// watchSkipDirs are never watched; they churn constantly and are never formatted.
+var watchSkipDirs = map[string]bool{".git": true, "node_modules": true, "dist": true, ".angular": true}
+
+// watchRepo re-processes each file under repoPath after it is modified, until
+// interrupted with Ctrl-C.
+func watchRepo() {
+    watcher, err := fsnotify.NewWatcher()
+    if err != nil {
+        warnf("Could not start watcher: %v\n", err)
+        setExitStatus(2)
+        return
+    }
+    defer watcher.Close()
+
+    if err := addWatchDirs(watcher, repoPath); err != nil {
+        warnf("Could not watch %s: %v\n", repoPath, err)
+        setExitStatus(2)
+        return
+    }
+
+    stopExitOnInterrupt()
+    ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+    defer stop()
+
+    logln("\nWatching for changes (Ctrl-C to stop)...")
+
+    // Each path gets its own debounce timer; expiry hands the path to the loop
+    // below so formatting always happens on a single goroutine.
+    timers := make(map[string]*time.Timer)
+    ready := make(chan string)
+
+    // Our own writes also produce events; skip files whose content is exactly
+    // what the last pass left behind.
+    lastHash := make(map[string]string)
+
+    for {
+        select {
+        case <-ctx.Done():
+            for _, t := range timers {
+                t.Stop()
+            }
+            logln("\nStopped watching.")
+            return
+
+        case event, ok := <-watcher.Events:
+            if !ok {
+                return
+            }
+            if event.Has(fsnotify.Create) {
+                if info, err := os.Stat(event.Name); err == nil && info.IsDir() {
+                    addWatchDirs(watcher, event.Name)
+                    continue
+                }
+            }
+            if !event.Has(fsnotify.Write) && !event.Has(fsnotify.Create) {
+                continue
+            }
+
+            path := event.Name
+            if toolFor(extOf(path)) == "" {
+                continue
+            }
+            if t, exists := timers[path]; exists {
+                t.Reset(watchDebounce)
+                continue
+            }
+            timers[path] = time.AfterFunc(watchDebounce, func() {
+                select {
+                case ready <- path:
+                case <-ctx.Done():
+                }
+            })
+
+        case path := <-ready:
+            delete(timers, path)
+            hash, err := hashFile(path)
+            if err != nil || hash == lastHash[path] {
+                continue
+            }
+            logf("\nChanged: %s\n", relPath(path))
+            resetResults()
+            processChanges([]string{path})
+            printResults()
+            lastHash[path], _ = hashFile(path)
+
+        case err, ok := <-watcher.Errors:
+            if !ok {
+                return
+            }
+            warnf("Watcher error: %v\n", err)
+        }
+    }
+}
+
+// addWatchDirs watches root and every directory below it, skipping watchSkipDirs.
+func addWatchDirs(watcher *fsnotify.Watcher, root string) error {
+    return filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+        if err != nil || !d.IsDir() {
+            return nil
+        }
+        if path != root && watchSkipDirs[d.Name()] {
+            return filepath.SkipDir
+        }
+        return watcher.Add(path)
+    })
+}